@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go-htmx-template/internal/db"
+	"go-htmx-template/internal/replica"
+)
+
+var errNoReplicaDir = errors.New("REPLICA_DIR is not set")
+
+// backup writes a verified snapshot of the database to a new file, which is
+// safe while the server is running.
+func backup(ctx context.Context, a *app) error {
+	if len(a.cfg.Args) != 1 {
+		return errUsage
+	}
+	path := a.cfg.Args[0]
+
+	return withDatabase(a, func(database db.Database) error {
+		if err := database.Backup(ctx, path); err != nil {
+			return err
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("checking backup: %w", err)
+		}
+		a.logger.Info("database backed up", "path", path, "bytes", info.Size())
+		return nil
+	})
+}
+
+// restoreCommand replaces the database with a backup, or with the replica as
+// of -to-time.
+func restoreCommand() command {
+	var toTime string
+	return command{
+		name:    "restore",
+		args:    "<backup> | -to-time <time>",
+		summary: "replace the database with a backup or the replica as of time; the server must be stopped",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&toTime, "to-time", "", "restore from REPLICA_DIR as of this RFC 3339 time, or now")
+		},
+		run: func(ctx context.Context, a *app) error { return restore(ctx, a, toTime) },
+	}
+}
+
+// restore replaces the database with the backup named in the arguments or,
+// when toTime is set, with the replica as of then. It refuses to run while
+// the server, or anything else, has the database open.
+func restore(ctx context.Context, a *app, toTime string) error {
+	if toTime != "" {
+		if len(a.cfg.Args) != 0 {
+			return errUsage
+		}
+		at, err := parseRestoreTime(toTime)
+		if err != nil {
+			return err
+		}
+		return restoreReplica(ctx, a, at)
+	}
+	if len(a.cfg.Args) != 1 {
+		return errUsage
+	}
+	path := a.cfg.Args[0]
+
+	if err := db.Restore(ctx, a.cfg.DBURL, path); err != nil {
+		return err
+	}
+	a.logger.Info("database restored", "from", path, "db", a.cfg.DBURL)
+	return nil
+}
+
+// parseRestoreTime accepts an RFC 3339 time or "now".
+func parseRestoreTime(value string) (time.Time, error) {
+	if value == "now" {
+		return time.Now(), nil
+	}
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: time must be RFC 3339 or now, got %q", errUsage, value)
+	}
+	return at, nil
+}
+
+// restoreReplica rebuilds the database as of at from REPLICA_DIR into a
+// temporary file beside it, then restores that like a backup.
+func restoreReplica(ctx context.Context, a *app, at time.Time) error {
+	if a.cfg.ReplicaDir == "" {
+		return errNoReplicaDir
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(a.cfg.DBURL), ".restore-*")
+	if err != nil {
+		return fmt.Errorf("creating temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	rebuilt := filepath.Join(tmp, "db.sqlite3")
+	restoredTo, err := replica.Restore(ctx, replica.NewDirStore(a.cfg.ReplicaDir), rebuilt, at)
+	if err != nil {
+		return fmt.Errorf("rebuilding from replica: %w", err)
+	}
+	if err = db.Restore(ctx, a.cfg.DBURL, rebuilt); err != nil {
+		return err
+	}
+	a.logger.Info("database restored", "from", a.cfg.ReplicaDir, "to_time", restoredTo, "db", a.cfg.DBURL)
+	return nil
+}
+
+// runBackups writes a backup to BACKUP_DIR every BACKUP_INTERVAL until ctx is
+// done, keeping the newest BACKUP_KEEP. Failures are logged and retried at the
+// next interval.
+func runBackups(ctx context.Context, a *app, database db.Database) {
+	ticker := time.NewTicker(a.cfg.BackupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			start := time.Now()
+			path, err := db.RotateBackup(ctx, database, a.cfg.BackupDir, a.cfg.BackupKeep, now)
+			if err != nil {
+				a.logger.Error("scheduled backup failed", "error", err)
+				continue
+			}
+			a.logger.Info("database backed up", "path", path, "duration", time.Since(start))
+		}
+	}
+}
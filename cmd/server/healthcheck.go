@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-htmx-template/internal/config"
+)
+
+const healthcheckTimeout = 5 * time.Second
+
+var errUnhealthy = errors.New("unhealthy")
+
+// healthcheck probes /health on the running server: the admin listener if one
+// is configured, otherwise the first public address. It needs no tools in the
+// container, so the image can declare a HEALTHCHECK.
+func healthcheck(ctx context.Context, a *app) error {
+	if len(a.cfg.Args) > 1 {
+		return errUsage
+	}
+
+	target, client := healthTarget(a.cfg)
+	if len(a.cfg.Args) == 1 {
+		target, client = a.cfg.Args[0], &http.Client{}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, healthcheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("probing %s: %w", target, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s returned %s", errUnhealthy, target, resp.Status)
+	}
+	return nil
+}
+
+// healthTarget returns the /health URL of the local server and a client that
+// can reach it, including over a unix socket.
+func healthTarget(cfg config.Config) (string, *http.Client) {
+	addr, scheme := cfg.AdminAddr, "http"
+	if addr == "" {
+		addr = cfg.Addrs[0]
+		if cfg.TLSEnabled() {
+			scheme = "https"
+		}
+	}
+
+	transport := &http.Transport{
+		// The certificate is for the public name, not the loopback address
+		// probed here; this only checks that the local process responds.
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec // local liveness probe
+	}
+	client := &http.Client{Transport: transport}
+
+	if path, ok := strings.CutPrefix(addr, "unix://"); ok {
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		}
+		return scheme + "://localhost/health", client
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return scheme + "://" + addr + "/health", client
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	return scheme + "://" + net.JoinHostPort(host, port) + "/health", client
+}
@@ -3,82 +3,148 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
-	"strconv"
+	"os/signal"
+	"strings"
+	"syscall"
 
-	"go-htmx-template/internal/db"
+	"go-htmx-template/internal/config"
 	"go-htmx-template/internal/log"
-	"go-htmx-template/internal/server"
-	"go-htmx-template/internal/server/router"
+	"go-htmx-template/internal/version"
 )
 
-const defaultRateLimit = 50
+var errUsage = errors.New("invalid usage")
 
-var errInvalidRateLimit = errors.New("invalid RATE_LIMIT value")
+// app is what a command runs with.
+type app struct {
+	cfg    config.Config
+	logger *slog.Logger
+	level  *slog.LevelVar
+	stdout io.Writer
+	// load reads the configuration again with the same flags.
+	load func() (config.Config, error)
+}
 
-func main() {
-	logger := log.New(
-		log.GetLevel(),
-		log.GetOutput(),
-	)
+type command struct {
+	name    string
+	args    string
+	summary string
+	// noConfig skips loading the configuration, so the command works even
+	// when it is invalid.
+	noConfig bool
+	// flags defines flags of the command itself.
+	flags func(fs *flag.FlagSet)
+	run   func(ctx context.Context, a *app) error
+}
 
-	if err := run(logger); err != nil {
-		logger.Error("server error", "error", err)
-		os.Exit(1)
+func commands() []command {
+	return []command{
+		{name: "serve", summary: "start the HTTP server (default)", run: serve},
+		{name: "migrate", args: "up|down [N] [-dry-run] | status", summary: "apply, revert, preview or report database migrations", run: migrate},
+		{name: "seed", args: "<file>", summary: "execute a SQL file against the database", run: seed},
+		{name: "backup", args: "<path>", summary: "write a verified copy of the database to path", run: backup},
+		restoreCommand(),
+		{name: "healthcheck", args: "[url]", summary: "probe /health and exit non-zero if unhealthy", run: healthcheck},
+		{name: "version", summary: "print the version", noConfig: true, run: printVersion},
 	}
 }
 
-func run(logger *slog.Logger) error {
-	database, err := openDatabase()
-	if err != nil {
-		return err
+func main() {
+	os.Exit(runMain(os.Args[1:]))
+}
+
+func runMain(args []string) int {
+	// Without a command, or with only flags, serve for compatibility.
+	name := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		usage(os.Stdout)
+		return 0
 	}
-	defer func() {
-		if cerr := database.Close(); cerr != nil {
-			logger.Error("failed to close the database", "error", cerr)
-		}
-	}()
 
-	port := envOrDefault("PORT", "8080")
-	rateLimit, err := parseRateLimit()
-	if err != nil {
-		return err
+	idx := -1
+	cmds := commands()
+	for i, c := range cmds {
+		if c.name == name {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		usage(os.Stderr)
+		return 2
 	}
+	cmd := cmds[idx]
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	a := &app{
+		stdout: os.Stdout,
+		load: func() (config.Config, error) {
+			if cmd.flags == nil {
+				return config.Load(name, args)
+			}
+			return config.Load(name, args, cmd.flags)
+		},
+	}
+	if !cmd.noConfig {
+		cfg, err := a.load()
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		if cfg.PrintConfig {
+			if err = cfg.Print(os.Stdout); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			return 0
+		}
+		a.cfg = cfg
+	}
 
-	svr := server.New(
-		logger,
-		":"+port,
-		server.WithRouter(router.New(ctx, logger, database, rateLimit)),
-	)
+	a.level = new(slog.LevelVar)
+	a.level.Set(a.cfg.LogLevel.ToSlog())
+	a.logger = log.New(a.level, a.cfg.LogOutput)
+	a.logger.Debug("configuration loaded", "command", name, "config", a.cfg)
 
-	return svr.StartAndWait()
-}
+	// The server handles its own signals; other commands stop on the first one.
+	ctx := context.Background()
+	if name != "serve" {
+		var stop context.CancelFunc
+		ctx, stop = signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+	}
 
-func openDatabase() (db.Database, error) {
-	url := envOrDefault("DB_URL", "./db.sqlite3")
-	return db.New(url)
+	if err := cmd.run(ctx, a); err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprintf(os.Stderr, "%v\nusage: %s %s\n", err, name, cmd.args)
+			return 2
+		}
+		a.logger.Error(name+" failed", "error", err)
+		return 1
+	}
+	return 0
 }
 
-func parseRateLimit() (int, error) {
-	rateLimitStr := os.Getenv("RATE_LIMIT")
-	if rateLimitStr == "" {
-		return defaultRateLimit, nil
-	}
-	parsed, err := strconv.Atoi(rateLimitStr)
-	if err != nil || parsed <= 0 {
-		return 0, fmt.Errorf("%w: %s", errInvalidRateLimit, rateLimitStr)
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: %s <command> [flags] [args]\n\ncommands:\n", os.Args[0])
+	for _, c := range commands() {
+		fmt.Fprintf(w, "  %-12s %-32s %s\n", c.name, c.args, c.summary)
 	}
-	return parsed, nil
+	fmt.Fprintf(w, "\nRun %s <command> -h to list the configuration flags.\n", os.Args[0])
 }
 
-func envOrDefault(key, defaultVal string) string {
-	if val := os.Getenv(key); val != "" {
-		return val
+func printVersion(_ context.Context, a *app) error {
+	if _, err := fmt.Fprintln(a.stdout, version.Value); err != nil {
+		return fmt.Errorf("printing version: %w", err)
 	}
-	return defaultVal
+	return nil
 }
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"go-htmx-template/internal/db"
+)
+
+func migrate(ctx context.Context, a *app) error {
+	// Configuration flags stop at the subcommand, so -dry-run is picked out
+	// of the remaining arguments here.
+	dryRun := false
+	args := slices.DeleteFunc(slices.Clone(a.cfg.Args), func(arg string) bool {
+		isDryRun := arg == "-dry-run" || arg == "--dry-run"
+		dryRun = dryRun || isDryRun
+		return isDryRun
+	})
+	if len(args) == 0 || len(args) > 2 {
+		return errUsage
+	}
+
+	// Up applies everything by default; down reverts one migration unless told
+	// otherwise, since reverting drops data.
+	var direction db.Direction
+	steps := 0
+	switch args[0] {
+	case "up":
+		direction = db.Up
+	case "down":
+		direction = db.Down
+		steps = 1
+	case "status":
+		if len(args) > 1 || dryRun {
+			return errUsage
+		}
+		return withDatabase(a, func(database db.Database) error {
+			return printMigrationStatus(ctx, a, database)
+		})
+	default:
+		return fmt.Errorf("%w: unknown migrate command %q", errUsage, args[0])
+	}
+	if len(args) == 2 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			return fmt.Errorf("%w: steps must be a positive integer, got %q", errUsage, args[1])
+		}
+		steps = n
+	}
+
+	return withDatabase(a, func(database db.Database) error {
+		if dryRun {
+			return printPlan(ctx, a, database, direction, steps)
+		}
+		if err := db.Migrate(ctx, database, direction, steps); err != nil {
+			return fmt.Errorf("migrating %s: %w", direction, err)
+		}
+		version, _, err := db.SchemaVersion(ctx, database)
+		if errors.Is(err, db.ErrNoSchemaVersion) {
+			a.logger.Info("migrations complete", "direction", direction, "version", "none")
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading schema version: %w", err)
+		}
+		a.logger.Info("migrations complete", "direction", direction, "version", version)
+		return nil
+	})
+}
+
+// printPlan writes the SQL a migration would execute, without running it.
+func printPlan(ctx context.Context, a *app, database db.Database, direction db.Direction, steps int) error {
+	planned, err := db.Plan(ctx, database, direction, steps)
+	if err != nil {
+		return fmt.Errorf("planning %s: %w", direction, err)
+	}
+	var b strings.Builder
+	if len(planned) == 0 {
+		b.WriteString("-- nothing to migrate\n")
+	}
+	for _, m := range planned {
+		fmt.Fprintf(&b, "-- %s %d_%s\n%s\n\n", m.Direction, m.Version, m.Name, strings.TrimSpace(m.SQL))
+	}
+	if _, err = io.WriteString(a.stdout, b.String()); err != nil {
+		return fmt.Errorf("printing plan: %w", err)
+	}
+	return nil
+}
+
+// printMigrationStatus writes the state of every migration followed by any
+// difference between the live schema and the migrations.
+func printMigrationStatus(ctx context.Context, a *app, database db.Database) error {
+	states, err := db.MigrationStatus(ctx, database)
+	if err != nil {
+		return fmt.Errorf("reading migration status: %w", err)
+	}
+	drift, err := db.SchemaDrift(ctx, database)
+	if err != nil {
+		return fmt.Errorf("checking schema: %w", err)
+	}
+
+	var b strings.Builder
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tSTATE")
+	for _, s := range states {
+		name, state := s.Name, "pending"
+		if name == "" {
+			name = "(not embedded)"
+		}
+		switch {
+		case s.Dirty:
+			state = "dirty"
+		case s.Applied:
+			state = "applied"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", s.Version, name, state)
+	}
+	_ = tw.Flush()
+
+	if len(drift) == 0 {
+		b.WriteString("\nschema: matches migrations\n")
+	} else {
+		b.WriteString("\nschema: differs from migrations\n")
+		for _, d := range drift {
+			fmt.Fprintf(&b, "  %s\n", d)
+		}
+	}
+	if _, err = io.WriteString(a.stdout, b.String()); err != nil {
+		return fmt.Errorf("printing status: %w", err)
+	}
+	return nil
+}
+
+// withDatabase opens the configured database for the duration of fn.
+func withDatabase(a *app, fn func(db.Database) error) error {
+	database, err := openDatabase(a)
+	if err != nil {
+		return err
+	}
+	return errors.Join(fn(database), database.Close())
+}
+
+func openDatabase(a *app) (db.Database, error) {
+	return db.New(a.cfg.DBURL, db.WithLogger(a.logger), db.WithSlowQueryThreshold(a.cfg.SlowQueryThreshold))
+}
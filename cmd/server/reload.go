@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go-htmx-template/internal/config"
+	"go-htmx-template/internal/server/middleware"
+)
+
+// watchReload reloads the configuration on SIGHUP until ctx is done, applying
+// the log level and rate limit and logging every other change as needing a
+// restart.
+func watchReload(ctx context.Context, a *app, limiter *middleware.RateLimiter) {
+	current := a.cfg
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	defer signal.Stop(sig)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sig:
+			current = reload(a, limiter, current)
+		}
+	}
+}
+
+func reload(a *app, limiter *middleware.RateLimiter, current config.Config) config.Config {
+	updated, err := a.load()
+	if err == nil {
+		var changes []config.Change
+		updated, changes, err = config.Reload(current, updated)
+		if err == nil {
+			applyReload(a.logger, a.level, limiter, updated, changes)
+			return updated
+		}
+	}
+	a.logger.Error("config reload failed; keeping current configuration", "error", err)
+	return current
+}
+
+func applyReload(
+	logger *slog.Logger,
+	level *slog.LevelVar,
+	limiter *middleware.RateLimiter,
+	cfg config.Config,
+	changes []config.Change,
+) {
+	if len(changes) == 0 {
+		logger.Info("config reloaded; nothing changed")
+		return
+	}
+	for _, c := range changes {
+		if !c.Applied {
+			logger.Warn("config change requires a restart", "key", c.Key, "from", c.From, "to", c.To)
+			continue
+		}
+		// Only touch what changed, so a level set through the admin listener
+		// survives unrelated reloads.
+		switch c.Key {
+		case "LOG_LEVEL":
+			level.Set(cfg.LogLevel.ToSlog())
+		case "RATE_LIMIT":
+			limiter.SetLimit(cfg.RateLimit)
+		}
+		logger.Info("config changed", "key", c.Key, "from", c.From, "to", c.To)
+	}
+}
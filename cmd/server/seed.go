@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go-htmx-template/internal/db"
+)
+
+// seed executes a SQL file in a single transaction, so a failing statement
+// leaves the database untouched.
+func seed(ctx context.Context, a *app) error {
+	if len(a.cfg.Args) != 1 {
+		return errUsage
+	}
+	file := a.cfg.Args[0]
+
+	script, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("reading seed file: %w", err)
+	}
+
+	return withDatabase(a, func(database db.Database) error {
+		tx, err := database.DB().BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("beginning transaction: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		if _, err = tx.ExecContext(ctx, string(script)); err != nil {
+			return fmt.Errorf("executing %s: %w", file, err)
+		}
+		if err = tx.Commit(); err != nil {
+			return fmt.Errorf("committing seed: %w", err)
+		}
+		a.logger.Info("database seeded", "file", file)
+		return nil
+	})
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"go-htmx-template/internal/config"
+	"go-htmx-template/internal/db"
+	"go-htmx-template/internal/health"
+	"go-htmx-template/internal/replica"
+	"go-htmx-template/internal/server"
+	"go-htmx-template/internal/server/middleware"
+	"go-htmx-template/internal/server/router"
+)
+
+// minDiskFree is the free space below which the readiness check fails, so the
+// SQLite file never fills its filesystem.
+const minDiskFree = 100 << 20
+
+var errSchemaDrift = errors.New("database schema differs from migrations")
+
+func serve(_ context.Context, a *app) error {
+	cfg, logger := a.cfg, a.logger
+	if len(cfg.Args) > 0 {
+		return errUsage
+	}
+
+	database, err := openDatabase(a)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err = prepareDatabase(ctx, a, database); err != nil {
+		return errors.Join(err, database.Close())
+	}
+
+	metrics := middleware.NewMetrics()
+	readiness := newReadiness(database, cfg.DBURL)
+	ipCfg := middleware.IPConfig{TrustProxyHeaders: cfg.TrustProxyHeaders}
+	long := middleware.LongRequests{Paths: cfg.LongRequestPaths, Timeout: cfg.LongRequestTimeout}
+	limiter := middleware.NewRateLimiter(ctx, logger, cfg.RateLimit, middleware.DefaultMaxEntries, ipCfg)
+	var workers sync.WaitGroup
+	workers.Go(func() { watchReload(ctx, a, limiter) })
+	if cfg.BackupDir != "" {
+		workers.Go(func() { runBackups(ctx, a, database) })
+	}
+	if cfg.ReplicaDir != "" {
+		replicator := replica.New(logger, database, cfg.DBURL, replica.NewDirStore(cfg.ReplicaDir),
+			cfg.ReplicaSnapshotInterval, cfg.ReplicaRetention)
+		workers.Go(func() { replicator.Run(ctx, cfg.ReplicaSyncInterval) })
+	}
+	if cfg.TrashRetention > 0 {
+		workers.Go(func() { purgeTrash(ctx, a, database) })
+	}
+
+	// Shutdown hooks run in reverse order once HTTP has drained: background
+	// workers tied to ctx stop first, abandoning any backup in progress, then
+	// the database closes.
+	opts := []server.Option{
+		server.WithRouter(router.New(logger, database, limiter, ipCfg, metrics, readiness, long)),
+		server.WithOnShutdown(readiness.SetDraining),
+		server.WithAddrs(cfg.Addrs[1:]...),
+		server.WithUnixSocketMode(cfg.SocketMode),
+		server.WithWriteTimeout(cfg.WriteTimeout),
+		server.WithReadTimeout(cfg.ReadTimeout),
+		server.WithIdleTimeout(cfg.IdleTimeout),
+		server.WithReadHeaderTimeout(cfg.ReadHeaderTimeout),
+		server.WithMaxHeaderBytes(cfg.MaxHeaderBytes),
+		server.WithShutdownTimeout(cfg.ShutdownTimeout),
+		server.WithShutdownHook("database", func(context.Context) error {
+			return database.Close()
+		}),
+		server.WithShutdownHook("background workers", func(context.Context) error {
+			cancel()
+			workers.Wait()
+			return nil
+		}),
+	}
+	if cfg.H2C {
+		opts = append(opts, server.WithH2C())
+	}
+	if cfg.TLSEnabled() {
+		opts = append(opts, server.WithTLS(cfg.TLSCertFile, cfg.TLSKeyFile))
+		if cfg.TLSRedirectAddr != "" {
+			opts = append(opts, server.WithHTTPRedirect(cfg.TLSRedirectAddr))
+		}
+	}
+
+	if cfg.AdminAddr != "" {
+		opts = append(opts, server.WithAdmin(cfg.AdminAddr, router.NewAdmin(logger, a.level, metrics, readiness)))
+	}
+
+	svr := server.New(logger, cfg.Addrs[0], opts...)
+
+	return svr.StartAndWait()
+}
+
+// prepareDatabase applies pending migrations if AUTO_MIGRATE is set, then
+// compares the schema with the one sqlc generated the queries from.
+func prepareDatabase(ctx context.Context, a *app, database db.Database) error {
+	if a.cfg.AutoMigrate {
+		if err := db.Migrate(ctx, database, db.Up, 0); err != nil {
+			return fmt.Errorf("migrating up: %w", err)
+		}
+		version, _, _ := db.SchemaVersion(ctx, database)
+		a.logger.Info("database migrated", "version", version)
+	}
+
+	if a.cfg.SchemaCheck == config.SchemaCheckOff {
+		return nil
+	}
+	drift, err := db.SchemaDrift(ctx, database)
+	if err != nil {
+		return fmt.Errorf("checking schema: %w", err)
+	}
+	for _, d := range drift {
+		a.logger.Warn("schema drift", "kind", d.Kind, "type", d.Type, "name", d.Name, "want", d.Want, "got", d.Got)
+	}
+	if len(drift) > 0 && a.cfg.SchemaCheck == config.SchemaCheckFail {
+		return fmt.Errorf("%w: %d objects differ; run migrate status for details", errSchemaDrift, len(drift))
+	}
+	return nil
+}
+
+func newReadiness(database db.Database, dbURL string) *health.Readiness {
+	readiness := health.NewReadiness()
+	readiness.Register("database", health.Ping(database), 0)
+	readiness.Register("migrations", health.Migrations(database), 0)
+	readiness.Register("disk", health.DiskSpace(filepath.Dir(dbURL), minDiskFree), 0)
+	return readiness
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"go-htmx-template/internal/db"
+)
+
+// trashPurgeInterval is how often the trash is checked for authors past
+// TRASH_RETENTION.
+const trashPurgeInterval = time.Hour
+
+// purgeTrash permanently deletes the authors that have been in the trash for
+// longer than TRASH_RETENTION, at startup and then every trashPurgeInterval
+// until ctx is done.
+func purgeTrash(ctx context.Context, a *app, database db.Database) {
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		// deleted_at is stored as CURRENT_TIMESTAMP text, in UTC.
+		before := time.Now().Add(-a.cfg.TrashRetention).UTC().Format(time.DateTime)
+		n, err := database.Queries().PurgeTrashedAuthors(ctx, before)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			a.logger.Error("purging trash failed", "error", err)
+		case n > 0:
+			a.logger.Info("purged deleted authors", "count", n, "deleted_before", before)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,119 @@
+//go:build e2e
+
+package e2e_test
+
+import (
+	"testing"
+
+	"github.com/playwright-community/playwright-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthors_ValidationErrorIsSwapped(t *testing.T) {
+	t.Parallel()
+	_, page := newPage(t)
+	_, err := page.Goto(getFullPath("/authors"))
+	require.NoError(t, err)
+
+	require.NoError(t, page.GetByRole("button", playwright.PageGetByRoleOptions{Name: "Add author"}).Click())
+	require.NoError(t, expect.Locator(page.GetByText("Name is required.")).ToBeVisible())
+}
+
+func TestAuthors_CreateEditDelete(t *testing.T) {
+	t.Parallel()
+	_, page := newPage(t)
+	_, err := page.Goto(getFullPath("/authors"))
+	require.NoError(t, err)
+
+	require.NoError(t, page.Locator("#author-name").Fill("E2E Author"))
+	require.NoError(t, page.Locator("#author-bio").Fill("Written by the test"))
+	require.NoError(t, page.GetByRole("button", playwright.PageGetByRoleOptions{Name: "Add author"}).Click())
+
+	row := page.Locator("#authors-body tr", playwright.PageLocatorOptions{HasText: "E2E Author"})
+	require.NoError(t, expect.Locator(row).ToBeVisible())
+	require.NoError(t, expect.Locator(page.Locator("#author-name")).ToHaveValue(""))
+
+	require.NoError(t, row.GetByRole("button", playwright.LocatorGetByRoleOptions{Name: "Edit"}).Click())
+	require.NoError(t, page.Locator(`#authors-body input[name="name"]`).Fill("E2E Renamed"))
+	require.NoError(t, page.GetByRole("button", playwright.PageGetByRoleOptions{Name: "Save"}).Click())
+
+	renamed := page.Locator("#authors-body tr", playwright.PageLocatorOptions{HasText: "E2E Renamed"})
+	require.NoError(t, expect.Locator(renamed).ToBeVisible())
+
+	page.OnDialog(func(dialog playwright.Dialog) {
+		_ = dialog.Accept()
+	})
+	require.NoError(t, renamed.GetByRole("button", playwright.LocatorGetByRoleOptions{Name: "Delete"}).Click())
+	require.NoError(t, expect.Locator(renamed).ToHaveCount(0))
+}
+
+func TestAuthors_SearchAsYouType(t *testing.T) {
+	t.Parallel()
+	_, page := newPage(t)
+	_, err := page.Goto(getFullPath("/authors"))
+	require.NoError(t, err)
+
+	require.NoError(t, page.Locator("#author-name").Fill("E2E Searchable"))
+	require.NoError(t, page.GetByRole("button", playwright.PageGetByRoleOptions{Name: "Add author"}).Click())
+	require.NoError(t, expect.Locator(page.Locator("#author-name")).ToHaveValue(""))
+
+	require.NoError(t, page.Locator("#author-search").PressSequentially("searcha"))
+	match := page.Locator("#author-search-results mark", playwright.PageLocatorOptions{HasText: "Searchable"})
+	require.NoError(t, expect.Locator(match).ToBeVisible())
+}
+
+func TestAuthors_UndoDelete(t *testing.T) {
+	t.Parallel()
+	_, page := newPage(t)
+	_, err := page.Goto(getFullPath("/authors"))
+	require.NoError(t, err)
+
+	require.NoError(t, page.Locator("#author-name").Fill("E2E Undone"))
+	require.NoError(t, page.GetByRole("button", playwright.PageGetByRoleOptions{Name: "Add author"}).Click())
+
+	row := page.Locator("#authors-body tr", playwright.PageLocatorOptions{HasText: "E2E Undone"})
+	require.NoError(t, expect.Locator(row).ToBeVisible())
+	page.OnDialog(func(dialog playwright.Dialog) {
+		_ = dialog.Accept()
+	})
+	require.NoError(t, row.GetByRole("button", playwright.LocatorGetByRoleOptions{Name: "Delete"}).Click())
+	require.NoError(t, expect.Locator(row).ToHaveCount(0))
+
+	require.NoError(t, page.Locator("#authors-toast").GetByRole("button", playwright.LocatorGetByRoleOptions{Name: "Undo"}).Click())
+	require.NoError(t, expect.Locator(row).ToBeVisible())
+}
+
+func TestAuthors_EditConflict(t *testing.T) {
+	t.Parallel()
+	_, page := newPage(t)
+	_, err := page.Goto(getFullPath("/authors"))
+	require.NoError(t, err)
+
+	require.NoError(t, page.Locator("#author-name").Fill("E2E Contended"))
+	require.NoError(t, page.GetByRole("button", playwright.PageGetByRoleOptions{Name: "Add author"}).Click())
+	row := page.Locator("#authors-body tr", playwright.PageLocatorOptions{HasText: "E2E Contended"})
+	require.NoError(t, expect.Locator(row).ToBeVisible())
+	id, err := row.GetAttribute("id")
+	require.NoError(t, err)
+	require.NoError(t, row.GetByRole("button", playwright.LocatorGetByRoleOptions{Name: "Edit"}).Click())
+
+	// Someone else saves first, from another page listing the newest first.
+	_, other := newPage(t)
+	_, err = other.Goto(getFullPath("/authors?sort=created&dir=desc"))
+	require.NoError(t, err)
+	otherRow := other.Locator("#" + id)
+	require.NoError(t, otherRow.GetByRole("button", playwright.LocatorGetByRoleOptions{Name: "Edit"}).Click())
+	require.NoError(t, otherRow.Locator(`input[name="name"]`).Fill("E2E Contended Theirs"))
+	require.NoError(t, otherRow.GetByRole("button", playwright.LocatorGetByRoleOptions{Name: "Save"}).Click())
+	require.NoError(t, expect.Locator(otherRow).ToContainText("E2E Contended Theirs"))
+
+	edit := page.Locator("#" + id)
+	require.NoError(t, edit.Locator(`input[name="name"]`).Fill("E2E Contended Mine"))
+	require.NoError(t, edit.GetByRole("button", playwright.LocatorGetByRoleOptions{Name: "Save"}).Click())
+	require.NoError(t, expect.Locator(edit.GetByRole("alert")).ToBeVisible())
+	require.NoError(t, expect.Locator(edit).ToContainText("E2E Contended Theirs"))
+
+	require.NoError(t, edit.GetByRole("button", playwright.LocatorGetByRoleOptions{Name: "Keep mine"}).Click())
+	require.NoError(t, expect.Locator(edit.GetByRole("button", playwright.LocatorGetByRoleOptions{Name: "Edit"})).ToBeVisible())
+	require.NoError(t, expect.Locator(edit).ToContainText("E2E Contended Mine"))
+}
@@ -61,7 +61,7 @@ func TestCounter_IncrementsMultipleTimes(t *testing.T) {
 // counterValue reads the current counter value from the rendered page.
 func counterValue(t *testing.T, page playwright.Page) int {
 	t.Helper()
-	text, err := page.Locator(`#counter p`).InnerText()
+	text, err := page.Locator(`#counter-home p`).InnerText()
 	require.NoError(t, err, "could not read counter element text")
 	parts := strings.SplitN(text, ": ", 2)
 	require.Len(t, parts, 2, "unexpected counter text format: %s", text)
@@ -0,0 +1,423 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-htmx-template/internal/log"
+	"go-htmx-template/internal/version"
+)
+
+const (
+	defaultEnvFile    = ".env"
+	defaultRateLimit  = 50
+	defaultBackupKeep = 24
+	fileSuffix        = "_FILE"
+)
+
+// Defaults for the server's timeouts and limits, which the server also uses
+// when they are not set.
+const (
+	DefaultWriteTimeout      = 15 * time.Second
+	DefaultReadTimeout       = 15 * time.Second
+	DefaultIdleTimeout       = 60 * time.Second
+	DefaultReadHeaderTimeout = 5 * time.Second
+	DefaultMaxHeaderBytes    = 1 << 20
+	DefaultShutdownTimeout   = 10 * time.Second
+)
+
+var (
+	errFileAndValue         = errors.New("set either the variable or its _FILE variant, not both")
+	errOutOfRange           = errors.New("out of range")
+	errNegativeDuration     = errors.New("must not be negative")
+	errNonPositiveDuration  = errors.New("must be positive")
+	errInvalidSocketMode    = errors.New("must be an octal file mode such as 0660")
+	errTLSPair              = errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	errRedirectWithoutTLS   = errors.New("TLS_REDIRECT_ADDR requires TLS_CERT_FILE and TLS_KEY_FILE")
+	errReadHeaderTimeout    = errors.New("READ_HEADER_TIMEOUT must not exceed READ_TIMEOUT")
+	errAdminAddrInUse       = errors.New("ADMIN_ADDR must differ from the public listen addresses")
+	errEmptyAddr            = errors.New("must not contain empty addresses")
+	errRelativePath         = errors.New("paths must start with /")
+	errDotenvMissingEquals  = errors.New("expected KEY=value")
+	errDotenvUnterminated   = errors.New("unterminated quoted value")
+	errDotenvInvalidKeyName = errors.New("invalid variable name")
+	errUnknownSchemaCheck   = errors.New("must be warn, fail or off")
+)
+
+// SchemaCheck is what the server does at startup when the database schema
+// differs from the embedded migrations.
+type SchemaCheck string
+
+const (
+	SchemaCheckWarn SchemaCheck = "warn"
+	SchemaCheckFail SchemaCheck = "fail"
+	SchemaCheckOff  SchemaCheck = "off"
+)
+
+// Config is the application configuration. Each value comes from, in order of
+// precedence, a command-line flag, an environment variable (or the file named
+// by its _FILE variant), a .env file, or its default.
+type Config struct {
+	// Addrs are the public listen addresses; they default to ":" + Port.
+	Addrs      []string
+	Port       int
+	SocketMode os.FileMode
+	AdminAddr  string
+	H2C        bool
+
+	WriteTimeout      time.Duration
+	ReadTimeout       time.Duration
+	IdleTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	MaxHeaderBytes    int
+	ShutdownTimeout   time.Duration
+
+	// LongRequestPaths are path prefixes given LongRequestTimeout instead of
+	// the read and write timeouts.
+	LongRequestPaths   []string
+	LongRequestTimeout time.Duration
+
+	TLSCertFile     string
+	TLSKeyFile      string
+	TLSRedirectAddr string
+
+	LogLevel  log.Level
+	LogOutput log.Output
+
+	DBURL              string
+	AutoMigrate        bool
+	SchemaCheck        SchemaCheck
+	SlowQueryThreshold time.Duration
+	RateLimit          int
+	TrustProxyHeaders  bool
+
+	// BackupDir enables scheduled backups when set.
+	BackupDir      string
+	BackupInterval time.Duration
+	BackupKeep     int
+
+	// ReplicaDir enables continuous replication when set.
+	ReplicaDir              string
+	ReplicaSyncInterval     time.Duration
+	ReplicaSnapshotInterval time.Duration
+	ReplicaRetention        time.Duration
+
+	// TrashRetention is how long deleted authors are kept; 0 keeps them.
+	TrashRetention time.Duration
+
+	// PrintConfig is set by the -print-config flag.
+	PrintConfig bool
+	// Args are the positional arguments left after the flags.
+	Args []string
+
+	values []value
+}
+
+// TLSEnabled reports whether HTTPS is configured.
+func (c Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// setting describes one configuration value.
+type setting struct {
+	key   string
+	def   string
+	usage string
+	apply func(c *Config, raw string) error
+	// redact hides sensitive parts of the value when printing.
+	redact func(string) string
+	// reload marks settings that Reload may change while the server runs.
+	reload bool
+}
+
+func settings() []setting {
+	return []setting{
+		{key: "PORT", def: "8080", usage: "HTTP port, ignored when ADDR is set", apply: intVal(func(c *Config) *int { return &c.Port }, 1, 65535)},
+		{key: "ADDR", usage: "comma-separated listen addresses, e.g. unix:///run/app.sock,127.0.0.1:8080", apply: parseAddrs},
+		{key: "UNIX_SOCKET_MODE", def: "0660", usage: "file mode (octal) for unix sockets", apply: parseSocketMode},
+		{key: "ADMIN_ADDR", usage: "address for the admin listener", apply: stringVal(func(c *Config) *string { return &c.AdminAddr })},
+		{key: "H2C", def: "false", usage: "also accept unencrypted HTTP/2", apply: boolVal(func(c *Config) *bool { return &c.H2C })},
+		{key: "WRITE_TIMEOUT", def: DefaultWriteTimeout.String(), usage: "maximum time to write a response", apply: durationVal(func(c *Config) *time.Duration { return &c.WriteTimeout }, false)},
+		{key: "READ_TIMEOUT", def: DefaultReadTimeout.String(), usage: "maximum time to read a request", apply: durationVal(func(c *Config) *time.Duration { return &c.ReadTimeout }, false)},
+		{key: "IDLE_TIMEOUT", def: DefaultIdleTimeout.String(), usage: "maximum keep-alive idle time", apply: durationVal(func(c *Config) *time.Duration { return &c.IdleTimeout }, false)},
+		{key: "READ_HEADER_TIMEOUT", def: DefaultReadHeaderTimeout.String(), usage: "maximum time to read request headers", apply: durationVal(func(c *Config) *time.Duration { return &c.ReadHeaderTimeout }, false)},
+		{key: "MAX_HEADER_BYTES", def: strconv.Itoa(DefaultMaxHeaderBytes), usage: "maximum size of request headers", apply: intVal(func(c *Config) *int { return &c.MaxHeaderBytes }, 1, 1<<30)},
+		{key: "SHUTDOWN_TIMEOUT", def: DefaultShutdownTimeout.String(), usage: "time allowed for graceful shutdown", apply: durationVal(func(c *Config) *time.Duration { return &c.ShutdownTimeout }, true)},
+		{key: "LONG_REQUEST_PATHS", usage: "comma-separated path prefixes allowed LONG_REQUEST_TIMEOUT, e.g. /uploads/", apply: parsePaths},
+		{key: "LONG_REQUEST_TIMEOUT", def: (5 * time.Minute).String(), usage: "read and write timeout for LONG_REQUEST_PATHS; 0 disables it", apply: durationVal(func(c *Config) *time.Duration { return &c.LongRequestTimeout }, false)},
+		{key: "TLS_CERT_FILE", usage: "PEM certificate file", apply: stringVal(func(c *Config) *string { return &c.TLSCertFile })},
+		{key: "TLS_KEY_FILE", usage: "PEM private key file", apply: stringVal(func(c *Config) *string { return &c.TLSKeyFile })},
+		{key: "TLS_REDIRECT_ADDR", usage: "HTTP address that redirects to HTTPS", apply: stringVal(func(c *Config) *string { return &c.TLSRedirectAddr })},
+		{key: "LOG_LEVEL", def: string(log.LevelInfo), usage: "log level: debug, info, warn, error", apply: parseLogLevel, reload: true},
+		{key: "LOG_OUTPUT", def: string(log.OutputText), usage: "log format: text or json", apply: parseLogOutput},
+		{key: "DB_URL", def: "./db.sqlite3", usage: "path to the SQLite database", apply: stringVal(func(c *Config) *string { return &c.DBURL }), redact: redactURL},
+		{key: "AUTO_MIGRATE", def: "false", usage: "apply pending database migrations at startup", apply: boolVal(func(c *Config) *bool { return &c.AutoMigrate })},
+		{key: "SCHEMA_CHECK", def: string(SchemaCheckWarn), usage: "on schema drift at startup: warn, fail or off", apply: parseSchemaCheck},
+		{key: "SLOW_QUERY_THRESHOLD", def: (100 * time.Millisecond).String(), usage: "log queries that take at least this long; 0 disables it", apply: durationVal(func(c *Config) *time.Duration { return &c.SlowQueryThreshold }, false)},
+		{key: "BACKUP_DIR", usage: "directory for scheduled backups; empty disables them", apply: stringVal(func(c *Config) *string { return &c.BackupDir })},
+		{key: "BACKUP_INTERVAL", def: time.Hour.String(), usage: "time between scheduled backups", apply: durationVal(func(c *Config) *time.Duration { return &c.BackupInterval }, true)},
+		{key: "BACKUP_KEEP", def: strconv.Itoa(defaultBackupKeep), usage: "number of scheduled backups to keep", apply: intVal(func(c *Config) *int { return &c.BackupKeep }, 1, 1<<20)},
+		{key: "REPLICA_DIR", usage: "directory to replicate the database to continuously; empty disables it", apply: stringVal(func(c *Config) *string { return &c.ReplicaDir })},
+		{key: "REPLICA_SYNC_INTERVAL", def: time.Second.String(), usage: "time between shipping WAL segments", apply: durationVal(func(c *Config) *time.Duration { return &c.ReplicaSyncInterval }, true)},
+		{key: "REPLICA_SNAPSHOT_INTERVAL", def: (24 * time.Hour).String(), usage: "time between replica snapshots", apply: durationVal(func(c *Config) *time.Duration { return &c.ReplicaSnapshotInterval }, true)},
+		{key: "REPLICA_RETENTION", def: (72 * time.Hour).String(), usage: "how far back the replica can restore to", apply: durationVal(func(c *Config) *time.Duration { return &c.ReplicaRetention }, true)},
+		{key: "TRASH_RETENTION", def: (30 * 24 * time.Hour).String(), usage: "how long deleted authors stay in the trash before they are purged; 0 keeps them", apply: durationVal(func(c *Config) *time.Duration { return &c.TrashRetention }, false)},
+		{key: "RATE_LIMIT", def: strconv.Itoa(defaultRateLimit), usage: "requests per minute per IP address", apply: intVal(func(c *Config) *int { return &c.RateLimit }, 1, 1<<30), reload: true},
+		{
+			key:   "TRUST_PROXY_HEADERS",
+			def:   strconv.FormatBool(version.Value != "dev"),
+			usage: "trust X-Forwarded-For and X-Real-IP; enable only behind a reverse proxy",
+			apply: boolVal(func(c *Config) *bool { return &c.TrustProxyHeaders }),
+		},
+	}
+}
+
+// Load resolves the configuration from args, the process environment and a
+// .env file in the working directory. name is the command shown in usage
+// messages; args exclude it. Each of commandFlags defines flags of the command
+// itself, which args may mix with the configuration flags.
+func Load(name string, args []string, commandFlags ...func(*flag.FlagSet)) (Config, error) {
+	return LoadFrom(name, args, os.LookupEnv, commandFlags...)
+}
+
+// LoadFrom is like Load but reads environment variables with lookupEnv. Every
+// invalid value is reported in the returned error, not just the first. It
+// returns flag.ErrHelp when args ask for usage.
+func LoadFrom(
+	name string,
+	args []string,
+	lookupEnv func(string) (string, bool),
+	commandFlags ...func(*flag.FlagSet),
+) (Config, error) {
+	var cfg Config
+	specs := settings()
+
+	flags := make(map[string]string)
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	for _, s := range specs {
+		usage := fmt.Sprintf("%s (env %s", s.usage, s.key)
+		if s.def != "" {
+			usage += ", default " + s.def
+		}
+		fs.Func(flagName(s.key), usage+")", func(v string) error {
+			flags[s.key] = v
+			return nil
+		})
+	}
+	envFile := fs.String("env-file", "", "load variables from this file instead of "+defaultEnvFile)
+	fs.BoolVar(&cfg.PrintConfig, "print-config", false, "print the effective configuration, with secrets redacted, and exit")
+	for _, define := range commandFlags {
+		define(fs)
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, fmt.Errorf("parsing flags: %w", err)
+	}
+	cfg.Args = fs.Args()
+
+	var errs []error
+	dotenv, envPath, err := loadDotenv(*envFile)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	lookup := func(key string) (string, string, bool) {
+		if v, ok := lookupEnv(key); ok && v != "" {
+			return v, sourceEnv, true
+		}
+		if v, ok := dotenv[key]; ok && v != "" {
+			return v, envPath, true
+		}
+		return "", "", false
+	}
+
+	for _, s := range specs {
+		raw, source, rerr := resolve(s, flags, lookup)
+		if rerr != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.key, rerr))
+			continue
+		}
+		if aerr := s.apply(&cfg, raw); aerr != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.key, aerr))
+		}
+		cfg.values = append(cfg.values, value{setting: s, raw: raw, source: source})
+	}
+	if len(cfg.Addrs) == 0 {
+		cfg.Addrs = []string{":" + strconv.Itoa(cfg.Port)}
+	}
+	errs = append(errs, cfg.validate()...)
+
+	if err = errors.Join(errs...); err != nil {
+		return Config{}, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, nil
+}
+
+// resolve returns the raw value for s and where it came from.
+func resolve(s setting, flags map[string]string, lookup func(string) (string, string, bool)) (string, string, error) {
+	if v, ok := flags[s.key]; ok {
+		return v, sourceFlag, nil
+	}
+
+	v, source, hasValue := lookup(s.key)
+	path, _, hasFile := lookup(s.key + fileSuffix)
+	switch {
+	case hasValue && hasFile:
+		return "", "", errFileAndValue
+	case hasFile:
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", "", fmt.Errorf("reading %s%s: %w", s.key, fileSuffix, err)
+		}
+		return strings.TrimRight(string(b), "\r\n"), s.key + fileSuffix, nil
+	case hasValue:
+		return v, source, nil
+	default:
+		return s.def, sourceDefault, nil
+	}
+}
+
+// validate checks constraints that span several settings.
+func (c Config) validate() []error {
+	var errs []error
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		errs = append(errs, errTLSPair)
+	}
+	if c.TLSRedirectAddr != "" && !c.TLSEnabled() {
+		errs = append(errs, errRedirectWithoutTLS)
+	}
+	if c.ReadTimeout > 0 && c.ReadHeaderTimeout > c.ReadTimeout {
+		errs = append(errs, fmt.Errorf("%w: %s > %s", errReadHeaderTimeout, c.ReadHeaderTimeout, c.ReadTimeout))
+	}
+	if c.AdminAddr != "" {
+		for _, addr := range c.Addrs {
+			if addr == c.AdminAddr {
+				errs = append(errs, fmt.Errorf("%w: %s", errAdminAddrInUse, addr))
+			}
+		}
+	}
+	return errs
+}
+
+// flagName converts an env var name such as RATE_LIMIT to a flag name such as
+// rate-limit.
+func flagName(key string) string {
+	return strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+}
+
+func stringVal(field func(*Config) *string) func(*Config, string) error {
+	return func(c *Config, raw string) error {
+		*field(c) = raw
+		return nil
+	}
+}
+
+func intVal(field func(*Config) *int, lo, hi int) func(*Config, string) error {
+	return func(c *Config, raw string) error {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q: %w", raw, err)
+		}
+		if n < lo || n > hi {
+			return fmt.Errorf("%w: %d not in [%d, %d]", errOutOfRange, n, lo, hi)
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func boolVal(field func(*Config) *bool) func(*Config, string) error {
+	return func(c *Config, raw string) error {
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q: %w", raw, err)
+		}
+		*field(c) = b
+		return nil
+	}
+}
+
+func durationVal(field func(*Config) *time.Duration, positive bool) func(*Config, string) error {
+	return func(c *Config, raw string) error {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q: %w", raw, err)
+		}
+		if d < 0 {
+			return fmt.Errorf("%w: %s", errNegativeDuration, d)
+		}
+		if positive && d == 0 {
+			return errNonPositiveDuration
+		}
+		*field(c) = d
+		return nil
+	}
+}
+
+func parseAddrs(c *Config, raw string) error {
+	if raw == "" {
+		return nil
+	}
+	for addr := range strings.SplitSeq(raw, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			return errEmptyAddr
+		}
+		c.Addrs = append(c.Addrs, addr)
+	}
+	return nil
+}
+
+func parsePaths(c *Config, raw string) error {
+	if raw == "" {
+		return nil
+	}
+	for path := range strings.SplitSeq(raw, ",") {
+		path = strings.TrimSpace(path)
+		if !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("%w: %q", errRelativePath, path)
+		}
+		c.LongRequestPaths = append(c.LongRequestPaths, path)
+	}
+	return nil
+}
+
+func parseSocketMode(c *Config, raw string) error {
+	mode, err := strconv.ParseUint(raw, 8, 32)
+	if err != nil || mode > uint64(os.ModePerm) {
+		return fmt.Errorf("%w: %q", errInvalidSocketMode, raw)
+	}
+	c.SocketMode = os.FileMode(mode)
+	return nil
+}
+
+func parseLogLevel(c *Config, raw string) error {
+	level, err := log.ParseLevel(raw)
+	if err != nil {
+		return fmt.Errorf("parsing log level: %w", err)
+	}
+	c.LogLevel = level
+	return nil
+}
+
+func parseLogOutput(c *Config, raw string) error {
+	output, err := log.ParseOutput(raw)
+	if err != nil {
+		return fmt.Errorf("parsing log output: %w", err)
+	}
+	c.LogOutput = output
+	return nil
+}
+
+func parseSchemaCheck(c *Config, raw string) error {
+	switch check := SchemaCheck(strings.ToLower(raw)); check {
+	case SchemaCheckWarn, SchemaCheckFail, SchemaCheckOff:
+		c.SchemaCheck = check
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", errUnknownSchemaCheck, raw)
+	}
+}
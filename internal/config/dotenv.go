@@ -0,0 +1,130 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// loadDotenv reads KEY=value pairs from path, or from .env in the working
+// directory when path is empty. A missing .env is not an error; a missing
+// explicit path is.
+func loadDotenv(path string) (map[string]string, string, error) {
+	name := path
+	if name == "" {
+		name = defaultEnvFile
+	}
+
+	f, err := os.Open(name)
+	if path == "" && errors.Is(err, fs.ErrNotExist) {
+		return nil, name, nil
+	}
+	if err != nil {
+		return nil, name, fmt.Errorf("opening env file: %w", err)
+	}
+	defer f.Close()
+
+	values, err := parseDotenv(f)
+	if err != nil {
+		return nil, name, fmt.Errorf("%s: %w", name, err)
+	}
+	return values, name, nil
+}
+
+// parseDotenv parses the common .env format: blank lines and # comments are
+// skipped, an optional "export " prefix is allowed, double-quoted values
+// support Go escapes, single-quoted values are literal, and unquoted values
+// end at " #".
+func parseDotenv(r io.Reader) (map[string]string, error) {
+	values := make(map[string]string)
+	var errs []error
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, raw, ok := strings.Cut(line, "=")
+		if !ok {
+			errs = append(errs, fmt.Errorf("line %d: %w", lineNo, errDotenvMissingEquals))
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if !validKey(key) {
+			errs = append(errs, fmt.Errorf("line %d: %w: %q", lineNo, errDotenvInvalidKeyName, key))
+			continue
+		}
+		val, err := parseDotenvValue(strings.TrimSpace(raw))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %s: %w", lineNo, key, err))
+			continue
+		}
+		values[key] = val
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, fmt.Errorf("reading env file: %w", err))
+	}
+
+	return values, errors.Join(errs...)
+}
+
+func parseDotenvValue(raw string) (string, error) {
+	if raw == "" {
+		return "", nil
+	}
+	switch quote := raw[0]; quote {
+	case '"', '\'':
+		end := closingQuote(raw, quote)
+		if end < 0 {
+			return "", errDotenvUnterminated
+		}
+		if quote == '\'' {
+			return raw[1:end], nil
+		}
+		val, err := strconv.Unquote(raw[:end+1])
+		if err != nil {
+			return "", fmt.Errorf("unquoting value: %w", err)
+		}
+		return val, nil
+	default:
+		if i := strings.Index(raw, " #"); i >= 0 {
+			raw = raw[:i]
+		}
+		return strings.TrimSpace(raw), nil
+	}
+}
+
+// closingQuote returns the index of the quote ending raw's quoted value, or -1.
+// Double-quoted values may contain escaped quotes.
+func closingQuote(raw string, quote byte) int {
+	for i := 1; i < len(raw); i++ {
+		switch {
+		case quote == '"' && raw[i] == '\\':
+			i++
+		case raw[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+func validKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i, r := range key {
+		isLetter := r == '_' || (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z')
+		if !isLetter && (i == 0 || r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	redacted = "[REDACTED]"
+	// urlRedacted replaces secrets inside URLs, where brackets would be escaped.
+	urlRedacted = "REDACTED"
+)
+
+// Where a value came from. Values read from a file name their _FILE variable
+// instead, and values from a .env file name that file.
+const (
+	sourceDefault = "default"
+	sourceEnv     = "env"
+	sourceFlag    = "flag"
+)
+
+// value is a resolved setting, kept for printing.
+type value struct {
+	setting setting
+
+	raw    string
+	source string
+}
+
+// display returns the value safe for printing. Values read through a _FILE
+// variable are secrets and always redacted.
+func (v value) display() string {
+	switch {
+	case v.raw == "":
+		return ""
+	case strings.HasSuffix(v.source, fileSuffix):
+		return redacted
+	case v.setting.redact != nil:
+		return v.setting.redact(v.raw)
+	default:
+		return v.raw
+	}
+}
+
+// Print writes the effective configuration as KEY=value lines, annotated with
+// where each value came from. Secrets are redacted.
+func (c Config) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, v := range c.values {
+		if _, err := fmt.Fprintf(tw, "%s=%s\t# %s\n", v.setting.key, v.display(), v.source); err != nil {
+			return fmt.Errorf("printing config: %w", err)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("printing config: %w", err)
+	}
+	return nil
+}
+
+// LogValue implements slog.LogValuer, logging the redacted configuration.
+func (c Config) LogValue() slog.Value {
+	attrs := make([]slog.Attr, 0, len(c.values))
+	for _, v := range c.values {
+		attrs = append(attrs, slog.String(v.setting.key, v.display()))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// redactURL hides the password and any credential-like query parameters in a
+// URL such as libsql://db.example.com?authToken=secret.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return redacted
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), urlRedacted)
+	}
+	query := u.Query()
+	for key := range query {
+		lower := strings.ToLower(key)
+		if strings.Contains(lower, "token") || strings.Contains(lower, "password") || strings.Contains(lower, "secret") {
+			query.Set(key, urlRedacted)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Change describes a setting whose value differs in a newly loaded
+// configuration. Values are redacted like Print.
+type Change struct {
+	Key  string
+	From string
+	To   string
+	// Applied is false for settings that only take effect after a restart.
+	Applied bool
+}
+
+// Reload returns current with the settings that can change at runtime (such
+// as LOG_LEVEL and RATE_LIMIT) taken from updated, along with every setting
+// that differs. Other settings keep their current values, so a later Reload
+// reports them again until the server is restarted.
+func Reload(current, updated Config) (Config, []Change, error) {
+	next := current
+	next.values = slices.Clone(current.values)
+
+	var changes []Change
+	for i, cur := range current.values {
+		j := slices.IndexFunc(updated.values, func(v value) bool { return v.setting.key == cur.setting.key })
+		if j < 0 || updated.values[j].raw == cur.raw {
+			continue
+		}
+		upd := updated.values[j]
+
+		change := Change{Key: cur.setting.key, From: cur.display(), To: upd.display(), Applied: cur.setting.reload}
+		if change.Applied {
+			if err := cur.setting.apply(&next, upd.raw); err != nil {
+				return current, nil, fmt.Errorf("%s: %w", cur.setting.key, err)
+			}
+			next.values[i] = upd
+		}
+		changes = append(changes, change)
+	}
+	return next, changes, nil
+}
@@ -0,0 +1,198 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+)
+
+const (
+	backupPrefix     = "backup-"
+	backupExt        = ".sqlite3"
+	backupTimeFormat = "20060102T150405Z"
+)
+
+var (
+	// ErrIntegrity is returned when a backup fails PRAGMA integrity_check or
+	// is not a SQLite database at all.
+	ErrIntegrity = errors.New("integrity check failed")
+	// ErrInUse is returned by Restore while another connection, such as a
+	// running server, has the database open.
+	ErrInUse = errors.New("database is in use; stop the server first")
+
+	errBackupExists = errors.New("backup file already exists")
+	errNoRestore    = errors.New("driver does not support restoring")
+)
+
+// Backup writes a consistent snapshot of the database to dest, which must not
+// exist, with VACUUM INTO and verifies it with PRAGMA integrity_check. It runs
+// on a connection of its own; in WAL mode that only reads, so the server keeps
+// serving reads and writes meanwhile. A snapshot that fails verification is
+// removed.
+func (d *localDB) Backup(ctx context.Context, dest string) error {
+	if _, err := os.Stat(dest); err == nil {
+		return fmt.Errorf("%w: %s", errBackupExists, dest)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("checking backup path: %w", err)
+	}
+
+	src := d.writer
+	if d.path != "" {
+		conn, err := sql.Open("sqlite", "file:"+d.path+"?"+pragmas)
+		if err != nil {
+			return fmt.Errorf("opening database: %w", err)
+		}
+		defer conn.Close()
+		src = conn
+	}
+
+	if _, err := src.ExecContext(ctx, "VACUUM INTO ?", dest); err != nil {
+		_ = os.Remove(dest)
+		return fmt.Errorf("backing up database: %w", err)
+	}
+	if err := VerifyBackup(ctx, dest); err != nil {
+		_ = os.Remove(dest)
+		return err
+	}
+	return nil
+}
+
+// VerifyBackup opens the backup at path read-only and runs PRAGMA
+// integrity_check on it, returning ErrIntegrity with the problems found.
+func VerifyBackup(ctx context.Context, path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("opening backup: %w", err)
+	}
+	backup, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("opening backup: %w", err)
+	}
+	defer backup.Close()
+
+	rows, err := backup.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrIntegrity, path, err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var problem string
+		if err = rows.Scan(&problem); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrIntegrity, path, err)
+		}
+		problems = append(problems, problem)
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrIntegrity, path, err)
+	}
+	if len(problems) != 1 || problems[0] != "ok" {
+		return fmt.Errorf("%w: %s: %s", ErrIntegrity, path, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// RotateBackup writes a backup named after now to dir, creating dir if
+// needed, then removes all but the newest keep backups there. It returns the
+// new backup's path.
+func RotateBackup(ctx context.Context, database Database, dir string, keep int, now time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", fmt.Errorf("creating backup directory: %w", err)
+	}
+	path := filepath.Join(dir, backupPrefix+now.UTC().Format(backupTimeFormat)+backupExt)
+	if err := database.Backup(ctx, path); err != nil {
+		return "", err
+	}
+	return path, pruneBackups(dir, keep)
+}
+
+// pruneBackups removes all but the newest keep backups in dir. Files not named
+// by RotateBackup are left alone.
+func pruneBackups(dir string, keep int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("listing backups: %w", err)
+	}
+
+	var backups []string
+	for _, entry := range entries {
+		stamp, ok := strings.CutPrefix(entry.Name(), backupPrefix)
+		stamp, ok2 := strings.CutSuffix(stamp, backupExt)
+		if !ok || !ok2 || entry.IsDir() {
+			continue
+		}
+		if _, perr := time.Parse(backupTimeFormat, stamp); perr == nil {
+			backups = append(backups, entry.Name())
+		}
+	}
+	// The timestamps sort chronologically.
+	slices.Sort(backups)
+
+	var errs []error
+	for _, name := range backups[:max(len(backups)-keep, 0)] {
+		if rerr := os.Remove(filepath.Join(dir, name)); rerr != nil {
+			errs = append(errs, fmt.Errorf("removing old backup: %w", rerr))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Restore replaces the database at path with the backup at src after
+// verifying the backup. It returns ErrInUse without changing anything if any
+// other connection has the database open.
+func Restore(ctx context.Context, path, src string) error {
+	if err := VerifyBackup(ctx, src); err != nil {
+		return err
+	}
+
+	target, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(0)")
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer target.Close()
+	conn, err := target.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer conn.Close()
+
+	// Leaving WAL mode needs the only connection to the database, so this
+	// fails while a server has it open; the exclusive lock then keeps others
+	// out until the restore is done.
+	if _, err = conn.ExecContext(ctx, "PRAGMA journal_mode=DELETE; PRAGMA locking_mode=EXCLUSIVE; BEGIN EXCLUSIVE; COMMIT"); err != nil {
+		if isBusy(err) {
+			return ErrInUse
+		}
+		return fmt.Errorf("locking database: %w", err)
+	}
+
+	err = conn.Raw(func(driverConn any) error {
+		restorer, ok := driverConn.(interface {
+			NewRestore(srcURI string) (*sqlite.Backup, error)
+		})
+		if !ok {
+			return errNoRestore
+		}
+		restore, rerr := restorer.NewRestore("file:" + src + "?mode=ro")
+		if rerr != nil {
+			return fmt.Errorf("opening backup: %w", rerr)
+		}
+		for more := true; more && rerr == nil; {
+			more, rerr = restore.Step(-1)
+		}
+		return errors.Join(rerr, restore.Finish())
+	})
+	if err != nil {
+		return fmt.Errorf("restoring database: %w", err)
+	}
+	return nil
+}
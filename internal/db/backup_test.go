@@ -0,0 +1,78 @@
+package db_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go-htmx-template/internal/db"
+	"go-htmx-template/internal/db/queries"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "db.sqlite3")
+	database := newMigratedDB(t, newTestDBAt(t, path))
+	_, err := database.Queries().CreateAuthor(t.Context(), queries.CreateAuthorParams{Name: "before"})
+	require.NoError(t, err)
+
+	dest := filepath.Join(dir, "backup.sqlite3")
+	require.NoError(t, database.Backup(t.Context(), dest))
+	require.NoError(t, db.VerifyBackup(t.Context(), dest))
+	require.Error(t, database.Backup(t.Context(), dest), "an existing file is never overwritten")
+
+	_, err = database.Queries().CreateAuthor(t.Context(), queries.CreateAuthorParams{Name: "after"})
+	require.NoError(t, err)
+
+	require.ErrorIs(t, db.Restore(t.Context(), path, dest), db.ErrInUse)
+	require.NoError(t, database.Close())
+	require.NoError(t, db.Restore(t.Context(), path, dest))
+
+	restored := newTestDBAt(t, path)
+	authors, err := restored.ReadQueries().ListAuthors(t.Context())
+	require.NoError(t, err)
+	require.Len(t, authors, 1)
+	assert.Equal(t, "before", authors[0].Name)
+}
+
+func TestVerifyBackup_RejectsCorruptFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "corrupt.sqlite3")
+	require.NoError(t, os.WriteFile(path, []byte("SQLite format 3\x00not really"), 0o600))
+
+	require.ErrorIs(t, db.VerifyBackup(t.Context(), path), db.ErrIntegrity)
+	require.ErrorIs(t, db.Restore(t.Context(), filepath.Join(t.TempDir(), "db.sqlite3"), path), db.ErrIntegrity)
+}
+
+func TestRotateBackup_KeepsNewest(t *testing.T) {
+	t.Parallel()
+
+	database := newMigratedDB(t, newTestDB(t))
+	dir := filepath.Join(t.TempDir(), "backups")
+	require.NoError(t, os.MkdirAll(dir, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0o600))
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var paths []string
+	for i := range 4 {
+		path, err := db.RotateBackup(t.Context(), database, dir, 2, start.Add(time.Duration(i)*time.Hour))
+		require.NoError(t, err)
+		paths = append(paths, path)
+	}
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.ElementsMatch(t, []string{"notes.txt", filepath.Base(paths[2]), filepath.Base(paths[3])}, names)
+	assert.Equal(t, "backup-20260101T030000Z.sqlite3", filepath.Base(paths[3]))
+}
@@ -7,19 +7,40 @@ import (
 	"go-htmx-template/internal/db/queries"
 )
 
+// Database is a SQLite database with a single-connection writer pool and a
+// pool of read-only connections.
 type Database interface {
+	// DB is the writer. Use it for anything that writes. Prefer InTx for
+	// transactions; the writer's only connection is held until one ends.
 	DB() *sql.DB
+	// ReadDB is the read-only pool.
+	ReadDB() *sql.DB
+	// Queries sends SELECTs to the readers and everything else to the writer.
 	Queries() *queries.Queries
+	ReadQueries() *queries.Queries
+	WriteQueries() *queries.Queries
+	// InTx runs fn in a transaction; see localDB.InTx.
+	InTx(ctx context.Context, opts *sql.TxOptions, fn TxFunc) error
+	// Backup writes a verified snapshot to dest; see localDB.Backup.
+	Backup(ctx context.Context, dest string) error
 	Close() error
 }
 
-func New(url string) (Database, error) {
-	db, err := newLocalDB(url)
+// New opens the SQLite database at url. Its queries count towards the
+// querystats.Stats of their context, and are logged when slow if so
+// configured.
+func New(url string, opts ...Option) (Database, error) {
+	db, err := newLocalDB(url, newInstrument(opts))
 	if err != nil {
 		return nil, err
 	}
-	if err = db.DB().PingContext(context.Background()); err != nil {
-		return nil, fmt.Errorf("pinging database: %w", err)
+	// The writer goes first: it switches the file to WAL mode, which the
+	// read-only connections cannot.
+	for _, pool := range []*sql.DB{db.DB(), db.ReadDB()} {
+		if err = pool.PingContext(context.Background()); err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("pinging database: %w", err)
+		}
 	}
 	return db, nil
 }
@@ -0,0 +1,134 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go-htmx-template/internal/db"
+	"go-htmx-template/internal/db/queries"
+)
+
+func newMigratedDB(t testing.TB, database db.Database) db.Database {
+	t.Helper()
+	require.NoError(t, db.Migrate(context.Background(), database, db.Up, 0))
+	return database
+}
+
+func TestQueries_RoutesReadsAndWrites(t *testing.T) {
+	t.Parallel()
+
+	database := newMigratedDB(t, newTestDB(t))
+
+	_, err := database.ReadQueries().CreateAuthor(t.Context(), queries.CreateAuthorParams{Name: "a"})
+	require.Error(t, err, "the read pool is read-only")
+
+	author, err := database.Queries().CreateAuthor(t.Context(), queries.CreateAuthorParams{Name: "a"})
+	require.NoError(t, err, "INSERT ... RETURNING goes to the writer")
+
+	// Hold the writer's only connection; reads must not wait for it.
+	tx, err := database.DB().BeginTx(t.Context(), nil)
+	require.NoError(t, err)
+	defer func() { _ = tx.Rollback() }()
+	_, err = database.WriteQueries().WithTx(tx).CreateAuthor(t.Context(), queries.CreateAuthorParams{Name: "b"})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+	defer cancel()
+	got, err := database.Queries().GetAuthor(ctx, author.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "a", got.Name)
+	authors, err := database.Queries().ListAuthors(ctx)
+	require.NoError(t, err)
+	assert.Len(t, authors, 1, "uncommitted writes are not visible to readers")
+}
+
+func TestQueries_PurgeTrashedAuthors(t *testing.T) {
+	t.Parallel()
+
+	database := newMigratedDB(t, newTestDB(t))
+	q := database.Queries()
+	for _, name := range []string{"kept", "recent", "old"} {
+		_, err := q.CreateAuthor(t.Context(), queries.CreateAuthorParams{Name: name})
+		require.NoError(t, err)
+	}
+	for _, id := range []int64{2, 3} {
+		_, err := q.TrashAuthor(t.Context(), id)
+		require.NoError(t, err)
+	}
+	_, err := database.DB().ExecContext(t.Context(),
+		"UPDATE authors SET deleted_at = datetime('now', '-2 days') WHERE id = 3")
+	require.NoError(t, err)
+
+	n, err := q.PurgeTrashedAuthors(t.Context(), time.Now().Add(-24*time.Hour).UTC().Format(time.DateTime))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	trashed, err := q.ListTrashedAuthors(t.Context())
+	require.NoError(t, err)
+	require.Len(t, trashed, 1)
+	assert.Equal(t, "recent", trashed[0].Name)
+	authors, err := q.ListAuthors(t.Context())
+	require.NoError(t, err)
+	require.Len(t, authors, 1)
+	assert.Equal(t, "kept", authors[0].Name)
+}
+
+// BenchmarkMixedLoad runs one write for every writeEvery reads from many
+// goroutines, against the split pools and against a single shared pool of the
+// same size, as the database was opened before the split.
+func BenchmarkMixedLoad(b *testing.B) {
+	const writeEvery = 4
+
+	pools := map[string]func(b *testing.B) db.Database{
+		"split": func(b *testing.B) db.Database {
+			b.Helper()
+			database, err := db.New(filepath.Join(b.TempDir(), "db.sqlite3"))
+			require.NoError(b, err)
+			return database
+		},
+		"shared": func(b *testing.B) db.Database {
+			b.Helper()
+			path := filepath.Join(b.TempDir(), "db.sqlite3")
+			raw, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+			require.NoError(b, err)
+			return db.NewFromRawDB(raw)
+		},
+	}
+
+	for _, name := range []string{"shared", "split"} {
+		b.Run(name, func(b *testing.B) {
+			database := newMigratedDB(b, pools[name](b))
+			defer database.Close()
+			q := database.Queries()
+			seed, err := q.CreateAuthor(b.Context(), queries.CreateAuthorParams{Name: "seed"})
+			require.NoError(b, err)
+
+			var n atomic.Int64
+			b.SetParallelism(8)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					var err error
+					i := n.Add(1)
+					if i%writeEvery == 0 {
+						_, err = q.CreateAuthor(b.Context(), queries.CreateAuthorParams{Name: strconv.FormatInt(i, 10)})
+					} else {
+						_, err = q.GetAuthor(b.Context(), seed.ID)
+					}
+					if err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go-htmx-template/internal/querystats"
+)
+
+var errUnsupportedConn = errors.New("driver connection lacks context methods")
+
+// Option configures New.
+type Option func(*instrument)
+
+// WithLogger sets the logger for slow queries. The default is slog.Default().
+func WithLogger(logger *slog.Logger) Option {
+	return func(in *instrument) {
+		in.logger = logger
+	}
+}
+
+// WithSlowQueryThreshold logs every query that takes at least d, with its
+// text, duration, row count and the request ID from querystats.NewContext.
+// Zero, the default, disables the log.
+func WithSlowQueryThreshold(d time.Duration) Option {
+	return func(in *instrument) {
+		in.slowQueryThreshold = d
+	}
+}
+
+// instrument times the queries on a pool's connections.
+type instrument struct {
+	logger             *slog.Logger
+	slowQueryThreshold time.Duration
+}
+
+func newInstrument(opts []Option) *instrument {
+	in := &instrument{logger: slog.Default()}
+	for _, opt := range opts {
+		opt(in)
+	}
+	return in
+}
+
+// record adds a query that started at start to the context's querystats and
+// logs it if it was slow. rows is the number affected or read.
+func (in *instrument) record(ctx context.Context, query string, start time.Time, rows int64, err error) {
+	elapsed := time.Since(start)
+	stats := querystats.FromContext(ctx)
+	if stats != nil {
+		stats.Add(elapsed)
+	}
+	if in.slowQueryThreshold <= 0 || elapsed < in.slowQueryThreshold {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("query", strings.Join(strings.Fields(query), " ")),
+		slog.Duration("duration", elapsed),
+		slog.Int64("rows", rows),
+	}
+	if stats != nil && stats.RequestID() != "" {
+		attrs = append(attrs, slog.String("request_id", stats.RequestID()))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	in.logger.LogAttrs(ctx, slog.LevelWarn, "slow query", attrs...)
+}
+
+// openPool opens a pool on dsn whose connections report to in.
+func openPool(dsn string, in *instrument) (*sql.DB, error) {
+	// The driver registered by modernc.org/sqlite has no exported
+	// constructor; opening a pool does not connect, so borrow it from one.
+	pool, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+	drv := pool.Driver()
+	if err = pool.Close(); err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+	return sql.OpenDB(connector{dsn: dsn, driver: drv, in: in}), nil
+}
+
+// sqliteConn is what database/sql uses of a driver connection.
+type sqliteConn interface {
+	driver.Conn
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.ExecerContext
+	driver.QueryerContext
+	driver.Pinger
+	driver.SessionResetter
+	driver.Validator
+}
+
+type connector struct {
+	dsn    string
+	driver driver.Driver
+	in     *instrument
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // database/sql wraps driver errors
+	}
+	sc, ok := conn.(sqliteConn)
+	if !ok {
+		return nil, errors.Join(errUnsupportedConn, conn.Close())
+	}
+	return instrumentedConn{sqliteConn: sc, in: c.in}, nil
+}
+
+func (c connector) Driver() driver.Driver {
+	return c.driver
+}
+
+type instrumentedConn struct {
+	sqliteConn
+	in *instrument
+}
+
+func (c instrumentedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	res, err := c.sqliteConn.ExecContext(ctx, query, args)
+	c.in.record(ctx, query, start, rowsAffected(res), err)
+	return res, err //nolint:wrapcheck // database/sql wraps driver errors
+}
+
+func (c instrumentedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	rows, err := c.sqliteConn.QueryContext(ctx, query, args)
+	if err != nil {
+		c.in.record(ctx, query, start, 0, err)
+		return nil, err //nolint:wrapcheck // database/sql wraps driver errors
+	}
+	return &instrumentedRows{Rows: rows, ctx: ctx, query: query, start: start, in: c.in}, nil
+}
+
+func (c instrumentedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.sqliteConn.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // database/sql wraps driver errors
+	}
+	return instrumentedStmt{Stmt: stmt, query: query, in: c.in}, nil
+}
+
+type instrumentedStmt struct {
+	driver.Stmt
+	query string
+	in    *instrument
+}
+
+func (s instrumentedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := s.Stmt.(driver.StmtExecContext)
+	if !ok {
+		return nil, errUnsupportedConn
+	}
+	start := time.Now()
+	res, err := execer.ExecContext(ctx, args)
+	s.in.record(ctx, s.query, start, rowsAffected(res), err)
+	return res, err //nolint:wrapcheck // database/sql wraps driver errors
+}
+
+func (s instrumentedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := s.Stmt.(driver.StmtQueryContext)
+	if !ok {
+		return nil, errUnsupportedConn
+	}
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, args)
+	if err != nil {
+		s.in.record(ctx, s.query, start, 0, err)
+		return nil, err //nolint:wrapcheck // database/sql wraps driver errors
+	}
+	return &instrumentedRows{Rows: rows, ctx: ctx, query: s.query, start: start, in: s.in}, nil
+}
+
+// instrumentedRows records its query when closed, since SQLite does most of
+// the work while the rows are read.
+type instrumentedRows struct {
+	driver.Rows
+	ctx   context.Context //nolint:containedctx // recorded on Close
+	query string
+	start time.Time
+	in    *instrument
+	read  int64
+	err   error
+}
+
+func (r *instrumentedRows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	switch {
+	case err == nil:
+		r.read++
+	case !errors.Is(err, io.EOF):
+		r.err = err
+	}
+	return err //nolint:wrapcheck // database/sql wraps driver errors
+}
+
+func (r *instrumentedRows) Close() error {
+	err := r.Rows.Close()
+	r.in.record(r.ctx, r.query, r.start, r.read, r.err)
+	return err //nolint:wrapcheck // database/sql wraps driver errors
+}
+
+func rowsAffected(res driver.Result) int64 {
+	if res == nil {
+		return 0
+	}
+	n, _ := res.RowsAffected()
+	return n
+}
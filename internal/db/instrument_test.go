@@ -0,0 +1,73 @@
+package db_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go-htmx-template/internal/db"
+	"go-htmx-template/internal/db/queries"
+	"go-htmx-template/internal/querystats"
+)
+
+func TestQueryStats(t *testing.T) {
+	t.Parallel()
+
+	var logs bytes.Buffer
+	database, err := db.New(filepath.Join(t.TempDir(), "db.sqlite3"),
+		db.WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+		db.WithSlowQueryThreshold(time.Nanosecond),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = database.Close() })
+	newMigratedDB(t, database)
+	logs.Reset()
+
+	ctx, stats := querystats.NewContext(t.Context(), "req-1")
+	for _, name := range []string{"a", "b"} {
+		_, err = database.Queries().CreateAuthor(ctx, queries.CreateAuthorParams{Name: name})
+		require.NoError(t, err)
+	}
+	err = database.InTx(ctx, nil, func(ctx context.Context, q *queries.Queries) error {
+		_, terr := q.ListAuthors(ctx)
+		return terr
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, stats.Count())
+	assert.Positive(t, stats.Duration())
+	assert.Contains(t, logs.String(), `msg="slow query" query="-- name: ListAuthors :many SELECT`)
+	assert.Contains(t, logs.String(), "rows=2 request_id=req-1")
+
+	// Queries without stats are still logged, without a request ID.
+	logs.Reset()
+	_, err = database.Queries().ListAuthors(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Count())
+	assert.Contains(t, logs.String(), "rows=2")
+	assert.NotContains(t, logs.String(), "request_id")
+}
+
+func TestWithSlowQueryThreshold_Disabled(t *testing.T) {
+	t.Parallel()
+
+	var logs bytes.Buffer
+	database, err := db.New(filepath.Join(t.TempDir(), "db.sqlite3"),
+		db.WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = database.Close() })
+	newMigratedDB(t, database)
+
+	ctx, stats := querystats.NewContext(t.Context(), "req-1")
+	_, err = database.Queries().ListAuthors(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Count())
+	assert.Empty(t, logs.String())
+}
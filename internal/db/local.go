@@ -1,48 +1,149 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"go-htmx-template/internal/db/queries"
+	"strings"
 
 	_ "modernc.org/sqlite"
 )
 
-const maxOpenConns = 4
+const (
+	maxReadConns = 4
+	pragmas      = "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+)
 
 type localDB struct {
-	db      *sql.DB
-	queries *queries.Queries
+	// path is empty for databases from NewFromRawDB.
+	path         string
+	writer       *sql.DB
+	reader       *sql.DB
+	queries      *queries.Queries
+	readQueries  *queries.Queries
+	writeQueries *queries.Queries
 }
 
 var _ Database = (*localDB)(nil)
 
 func (d *localDB) DB() *sql.DB {
-	return d.db
+	return d.writer
+}
+
+func (d *localDB) ReadDB() *sql.DB {
+	return d.reader
 }
 
 func (d *localDB) Queries() *queries.Queries {
 	return d.queries
 }
 
+func (d *localDB) ReadQueries() *queries.Queries {
+	return d.readQueries
+}
+
+func (d *localDB) WriteQueries() *queries.Queries {
+	return d.writeQueries
+}
+
 func (d *localDB) Close() error {
-	if err := d.db.Close(); err != nil {
+	err := d.reader.Close()
+	if d.writer != d.reader {
+		err = errors.Join(err, d.writer.Close())
+	}
+	if err != nil {
 		return fmt.Errorf("closing database: %w", err)
 	}
 	return nil
 }
 
-func newLocalDB(path string) (*localDB, error) {
-	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+func newLocalDB(path string, in *instrument) (*localDB, error) {
+	// SQLite allows one writer at a time. A single writer connection queues
+	// writes in the pool instead of contending for the lock until busy_timeout,
+	// and _txlock=immediate takes the lock at BEGIN so a transaction never fails
+	// part way when it upgrades from reading to writing.
+	writer, err := openPool("file:"+path+"?"+pragmas+"&_pragma=journal_mode(WAL)&_txlock=immediate", in)
+	if err != nil {
+		return nil, err
+	}
+	writer.SetMaxOpenConns(1)
+
+	// In WAL mode readers do not block the writer or each other.
+	reader, err := openPool("file:"+path+"?"+pragmas+"&_pragma=query_only(1)", in)
 	if err != nil {
-		return nil, fmt.Errorf("opening database: %w", err)
+		return nil, errors.Join(err, writer.Close())
+	}
+	reader.SetMaxOpenConns(maxReadConns)
+
+	db := newSplitDB(writer, reader)
+	db.path = path
+	return db, nil
+}
+
+func newSplitDB(writer, reader *sql.DB) *localDB {
+	return &localDB{
+		writer:       writer,
+		reader:       reader,
+		queries:      queries.New(router{writer: writer, reader: reader}),
+		readQueries:  queries.New(reader),
+		writeQueries: queries.New(writer),
 	}
-	db.SetMaxOpenConns(maxOpenConns)
-	return &localDB{db: db, queries: queries.New(db)}, nil
 }
 
-// NewFromRawDB creates a Database from an existing *sql.DB. Useful for testing.
+// NewFromRawDB creates a Database from an existing *sql.DB, which serves both
+// reads and writes. Its queries are not instrumented. Useful for testing.
 func NewFromRawDB(rawDB *sql.DB) Database {
-	rawDB.SetMaxOpenConns(maxOpenConns)
-	return &localDB{db: rawDB, queries: queries.New(rawDB)}
+	rawDB.SetMaxOpenConns(maxReadConns)
+	return newSplitDB(rawDB, rawDB)
+}
+
+// router is a queries.DBTX that sends plain SELECTs to the reader pool and
+// everything else to the writer.
+type router struct {
+	writer *sql.DB
+	reader *sql.DB
+}
+
+func (r router) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return r.writer.ExecContext(ctx, query, args...) //nolint:wrapcheck // proxying the pool; callers wrap
+}
+
+func (r router) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return r.pick(query).PrepareContext(ctx, query) //nolint:wrapcheck // proxying the pool; callers wrap
+}
+
+func (r router) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return r.pick(query).QueryContext(ctx, query, args...) //nolint:wrapcheck // proxying the pool; callers wrap
+}
+
+func (r router) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return r.pick(query).QueryRowContext(ctx, query, args...)
+}
+
+func (r router) pick(query string) *sql.DB {
+	if isRead(query) {
+		return r.reader
+	}
+	return r.writer
+}
+
+// isRead reports whether query starts with SELECT once leading comments, such
+// as sqlc's "-- name:" line, are skipped. WITH is treated as a write since it
+// may precede INSERT, UPDATE or DELETE.
+func isRead(query string) bool {
+	for {
+		query = strings.TrimSpace(query)
+		switch {
+		case strings.HasPrefix(query, "--"):
+			_, query, _ = strings.Cut(query, "\n")
+		case strings.HasPrefix(query, "/*"):
+			_, query, _ = strings.Cut(query, "*/")
+		default:
+			keyword, _, _ := strings.Cut(query, " ")
+			keyword, _, _ = strings.Cut(keyword, "\n")
+			return strings.EqualFold(keyword, "SELECT")
+		}
+	}
 }
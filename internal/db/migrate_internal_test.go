@@ -0,0 +1,110 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testMigrations(secondUp string) fstest.MapFS {
+	return fstest.MapFS{
+		"migrations/1_first.up.sql":    {Data: []byte("CREATE TABLE first (id INTEGER);")},
+		"migrations/1_first.down.sql":  {Data: []byte("DROP TABLE first;")},
+		"migrations/2_second.up.sql":   {Data: []byte(secondUp)},
+		"migrations/2_second.down.sql": {Data: []byte("DROP TABLE second;")},
+	}
+}
+
+func openTestDB(t *testing.T, path string) Database {
+	t.Helper()
+	database, err := New(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = database.Close() })
+	return database
+}
+
+func TestMigrate_FailedFileIsRolledBack(t *testing.T) {
+	t.Parallel()
+
+	database := openTestDB(t, filepath.Join(t.TempDir(), "db.sqlite3"))
+	// The second file fails after creating its table; that must be undone too.
+	fsys := testMigrations("CREATE TABLE second (id INTEGER); INSERT INTO missing VALUES (1);")
+
+	err := migrate(t.Context(), database, fsys, Up, 0)
+	require.ErrorIs(t, err, errMigrationFailed)
+	assert.Contains(t, err.Error(), "2_second")
+
+	version, dirty, err := SchemaVersion(t.Context(), database)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), version, "the first file stays applied")
+	assert.False(t, dirty)
+
+	_, err = database.DB().ExecContext(t.Context(), "SELECT 1 FROM second")
+	require.Error(t, err, "the failed file's statements are rolled back")
+}
+
+func TestMigrate_Steps(t *testing.T) {
+	t.Parallel()
+
+	database := openTestDB(t, filepath.Join(t.TempDir(), "db.sqlite3"))
+	fsys := testMigrations("CREATE TABLE second (id INTEGER);")
+
+	versions := []struct {
+		direction Direction
+		steps     int
+		expected  uint64
+		none      bool
+	}{
+		{direction: Up, steps: 1, expected: 1},
+		{direction: Up, steps: 1, expected: 2},
+		{direction: Up, steps: 1, expected: 2},
+		{direction: Down, steps: 1, expected: 1},
+		{direction: Down, steps: 5, none: true},
+	}
+	for _, v := range versions {
+		require.NoError(t, migrate(t.Context(), database, fsys, v.direction, v.steps))
+		version, _, err := SchemaVersion(t.Context(), database)
+		if v.none {
+			require.ErrorIs(t, err, ErrNoSchemaVersion)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, v.expected, version)
+	}
+}
+
+func TestMigrate_WaitsForWriteLock(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "db.sqlite3")
+	fsys := testMigrations("CREATE TABLE second (id INTEGER);")
+
+	// Another process holding the write lock, e.g. a second replica migrating.
+	conn, err := openTestDB(t, path).DB().Conn(t.Context())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.ExecContext(t.Context(), "BEGIN IMMEDIATE")
+	require.NoError(t, err)
+
+	database := openTestDB(t, path)
+	done := make(chan error, 1)
+	go func() { done <- migrate(t.Context(), database, fsys, Up, 0) }()
+
+	select {
+	case err = <-done:
+		t.Fatalf("migrate finished while the lock was held: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	_, err = conn.ExecContext(t.Context(), "COMMIT")
+	require.NoError(t, err)
+	require.NoError(t, <-done)
+
+	version, _, err := SchemaVersion(t.Context(), database)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), version)
+}
@@ -0,0 +1,399 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// nilVersion is how golang-migrate records a dirty database with no version.
+const nilVersion = -1
+
+// Direction is the direction migrations are applied in.
+type Direction string
+
+const (
+	// Up applies pending migrations.
+	Up Direction = "up"
+	// Down reverts applied migrations.
+	Down Direction = "down"
+)
+
+var (
+	// ErrNoSchemaVersion is returned when the database has no recorded migration
+	// version, i.e. migrations have never been applied.
+	ErrNoSchemaVersion = errors.New("no schema version recorded")
+	// ErrDirty is returned when a previous migration failed part way. The schema
+	// must be repaired by hand before migrating again.
+	ErrDirty = errors.New("database is dirty")
+
+	errUnknownDirection = errors.New("unknown migration direction")
+	errMissingFile      = errors.New("migration file missing")
+	errMigrationFailed  = errors.New("migration failed")
+)
+
+// migrateMu serializes migrations within the process; the SQLite write lock
+// serializes them across processes.
+var migrateMu sync.Mutex
+
+// migration is a pair of up and down files sharing a version.
+type migration struct {
+	version uint64
+	name    string
+	up      string
+	down    string
+}
+
+// querier is satisfied by *sql.DB, *sql.Conn and *sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// loadMigrations returns the migrations in fsys ordered by version. Files are
+// named migrations/<version>_<name>.up.sql and .down.sql, as created by
+// golang-migrate.
+func loadMigrations(fsys fs.FS) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("reading migrations: %w", err)
+	}
+
+	byVersion := make(map[uint64]*migration)
+	for _, entry := range entries {
+		versionStr, rest, ok := strings.Cut(entry.Name(), "_")
+		if !ok {
+			continue
+		}
+		version, perr := strconv.ParseUint(versionStr, 10, 64)
+		if perr != nil {
+			return nil, fmt.Errorf("parsing migration version from %s: %w", entry.Name(), perr)
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &migration{version: version}
+			byVersion[version] = m
+		}
+		file := path.Join("migrations", entry.Name())
+		switch {
+		case strings.HasSuffix(rest, ".up.sql"):
+			m.up = file
+			m.name = strings.TrimSuffix(rest, ".up.sql")
+		case strings.HasSuffix(rest, ".down.sql"):
+			m.down = file
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
+	}
+	slices.SortFunc(migrations, func(a, b migration) int {
+		return cmp.Compare(a.version, b.version)
+	})
+	return migrations, nil
+}
+
+// LatestMigrationVersion returns the highest version among the embedded
+// migrations.
+func LatestMigrationVersion() (uint64, error) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil || len(migrations) == 0 {
+		return 0, err
+	}
+	return migrations[len(migrations)-1].version, nil
+}
+
+// SchemaVersion returns the migration version and dirty flag recorded in the
+// schema_migrations table maintained by golang-migrate.
+func SchemaVersion(ctx context.Context, database Database) (uint64, bool, error) {
+	return schemaVersion(ctx, database.ReadDB())
+}
+
+func schemaVersion(ctx context.Context, q querier) (uint64, bool, error) {
+	var (
+		version int64
+		dirty   bool
+	)
+	err := q.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) || (err != nil && strings.Contains(err.Error(), "no such table")) {
+		return 0, false, ErrNoSchemaVersion
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("reading schema version: %w", err)
+	}
+	if version == nilVersion {
+		return 0, dirty, nil
+	}
+	return uint64(version), dirty, nil //nolint:gosec // versions are timestamps, never negative
+}
+
+// Migrate applies up to steps embedded migrations in direction, or all of
+// them when steps is zero or less.
+//
+// The version is recorded in the same schema_migrations table as
+// golang-migrate, so the two can be used interchangeably. Migrate holds the
+// database's write lock for the whole run, so concurrent runs, such as several
+// replicas auto-migrating at startup, wait for each other and then find
+// nothing left to do. Each file runs in its own nested transaction (a
+// savepoint) together with its version update: if one fails, it is rolled
+// back, the files before it stay applied and the schema is left clean.
+// Migrate returns ErrDirty without changing anything if golang-migrate left
+// the database dirty.
+func Migrate(ctx context.Context, database Database, direction Direction, steps int) error {
+	return migrate(ctx, database, migrationFiles, direction, steps)
+}
+
+func migrate(ctx context.Context, database Database, fsys fs.FS, direction Direction, steps int) (err error) {
+	if direction != Up && direction != Down {
+		return fmt.Errorf("%w: %q", errUnknownDirection, direction)
+	}
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		return err
+	}
+
+	migrateMu.Lock()
+	defer migrateMu.Unlock()
+
+	conn, err := database.DB().Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("acquiring connection: %w", err)
+	}
+	defer conn.Close()
+
+	// BEGIN IMMEDIATE takes the write lock up front, waiting out busy_timeout
+	// if another process holds it.
+	if _, err = conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		return fmt.Errorf("locking database: %w", err)
+	}
+	defer func() {
+		// The savepoints already rolled back any failed file, so commit what
+		// succeeded; only a failure before the first file discards everything.
+		stmt := "COMMIT"
+		if err != nil && !errors.Is(err, errMigrationFailed) {
+			stmt = "ROLLBACK"
+		}
+		if _, cerr := conn.ExecContext(context.WithoutCancel(ctx), stmt); cerr != nil {
+			err = errors.Join(err, fmt.Errorf("unlocking database: %w", cerr))
+		}
+	}()
+
+	if err = ensureVersionTable(ctx, conn); err != nil {
+		return err
+	}
+	todo, err := pending(ctx, conn, migrations, direction, steps)
+	if err != nil {
+		return err
+	}
+	for _, step := range todo {
+		if err = runMigration(ctx, conn, fsys, step); err != nil {
+			return fmt.Errorf("%w: %s %d_%s: %w", errMigrationFailed, direction, step.version, step.name, err)
+		}
+	}
+	return nil
+}
+
+// MigrationState is the state of one embedded migration in a database.
+type MigrationState struct {
+	Version uint64
+	// Name is empty for a version recorded in the database but not embedded,
+	// such as one applied by a newer build.
+	Name    string
+	Applied bool
+	Dirty   bool
+}
+
+// MigrationStatus reports every embedded migration as applied or pending,
+// flagging the recorded version if it is dirty. golang-migrate records only
+// the latest version, so every migration up to it counts as applied.
+func MigrationStatus(ctx context.Context, database Database) ([]MigrationState, error) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return nil, err
+	}
+	current, dirty, err := schemaVersion(ctx, database.ReadDB())
+	applied := err == nil
+	if err != nil && !errors.Is(err, ErrNoSchemaVersion) {
+		return nil, err
+	}
+
+	states := make([]MigrationState, 0, len(migrations)+1)
+	for _, m := range migrations {
+		states = append(states, MigrationState{
+			Version: m.version,
+			Name:    m.name,
+			Applied: applied && m.version <= current,
+			Dirty:   applied && m.version == current && dirty,
+		})
+	}
+	// Also report a recorded version that is not embedded, including the dirty
+	// nil version, which reads as 0.
+	embedded := slices.ContainsFunc(migrations, func(m migration) bool { return m.version == current })
+	if applied && !embedded && (current > 0 || dirty) {
+		states = append(states, MigrationState{Version: current, Applied: true, Dirty: dirty})
+		slices.SortFunc(states, func(a, b MigrationState) int {
+			return cmp.Compare(a.Version, b.Version)
+		})
+	}
+	return states, nil
+}
+
+// PlannedMigration is a file Migrate would run.
+type PlannedMigration struct {
+	Version   uint64
+	Name      string
+	Direction Direction
+	SQL       string
+}
+
+// Plan returns the files Migrate would run with the same arguments, without
+// changing the database.
+func Plan(ctx context.Context, database Database, direction Direction, steps int) ([]PlannedMigration, error) {
+	return planFiles(ctx, database, migrationFiles, direction, steps)
+}
+
+func planFiles(ctx context.Context, database Database, fsys fs.FS, direction Direction, steps int) ([]PlannedMigration, error) {
+	if direction != Up && direction != Down {
+		return nil, fmt.Errorf("%w: %q", errUnknownDirection, direction)
+	}
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+	todo, err := pending(ctx, database.ReadDB(), migrations, direction, steps)
+	if err != nil {
+		return nil, err
+	}
+
+	planned := make([]PlannedMigration, 0, len(todo))
+	for _, s := range todo {
+		if s.file == "" {
+			return nil, fmt.Errorf("%w: %s %d_%s", errMissingFile, direction, s.version, s.name)
+		}
+		script, rerr := fs.ReadFile(fsys, s.file)
+		if rerr != nil {
+			return nil, fmt.Errorf("reading %s: %w", s.file, rerr)
+		}
+		planned = append(planned, PlannedMigration{Version: s.version, Name: s.name, Direction: direction, SQL: string(script)})
+	}
+	return planned, nil
+}
+
+// step is one file to run and the version recorded once it succeeds.
+type step struct {
+	migration
+	file   string
+	target int64
+}
+
+// pending returns the steps that would move the database recorded in q up or
+// down by steps. It refuses a dirty database.
+func pending(ctx context.Context, q querier, migrations []migration, direction Direction, steps int) ([]step, error) {
+	current, dirty, err := schemaVersion(ctx, q)
+	if err != nil && !errors.Is(err, ErrNoSchemaVersion) {
+		return nil, err
+	}
+	if dirty {
+		return nil, fmt.Errorf("%w at version %d", ErrDirty, current)
+	}
+	return plan(migrations, current, direction, steps), nil
+}
+
+// plan lists the files to run to move current up or down by steps, or all
+// the way when steps is zero or less.
+func plan(migrations []migration, current uint64, direction Direction, steps int) []step {
+	var planned []step
+	if direction == Up {
+		for _, m := range migrations {
+			if m.version > current {
+				planned = append(planned, step{migration: m, file: m.up, target: int64(m.version)}) //nolint:gosec // versions are timestamps
+			}
+		}
+	} else {
+		for i, m := range slices.Backward(migrations) {
+			if m.version > current {
+				continue
+			}
+			target := int64(nilVersion)
+			if i > 0 {
+				target = int64(migrations[i-1].version) //nolint:gosec // versions are timestamps
+			}
+			planned = append(planned, step{migration: m, file: m.down, target: target})
+		}
+	}
+
+	if steps > 0 && len(planned) > steps {
+		planned = planned[:steps]
+	}
+	return planned
+}
+
+// runMigration executes one file and records its target version in a single
+// savepoint, so either both happen or neither does.
+func runMigration(ctx context.Context, conn *sql.Conn, fsys fs.FS, s step) error {
+	if s.file == "" {
+		return errMissingFile
+	}
+	script, err := fs.ReadFile(fsys, s.file)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", s.file, err)
+	}
+
+	if _, err = conn.ExecContext(ctx, "SAVEPOINT migration"); err != nil {
+		return fmt.Errorf("starting savepoint: %w", err)
+	}
+	if err = execMigration(ctx, conn, string(script), s.target); err != nil {
+		_, rerr := conn.ExecContext(context.WithoutCancel(ctx), "ROLLBACK TO migration; RELEASE migration")
+		return errors.Join(err, rerr)
+	}
+	if _, err = conn.ExecContext(ctx, "RELEASE migration"); err != nil {
+		return fmt.Errorf("releasing savepoint: %w", err)
+	}
+	return nil
+}
+
+func execMigration(ctx context.Context, q querier, script string, target int64) error {
+	if _, err := q.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("executing migration: %w", err)
+	}
+	return setVersion(ctx, q, target, false)
+}
+
+func ensureVersionTable(ctx context.Context, q querier) error {
+	const query = `CREATE TABLE IF NOT EXISTS schema_migrations (version uint64, dirty bool);
+CREATE UNIQUE INDEX IF NOT EXISTS version_unique ON schema_migrations (version);`
+	if _, err := q.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// setVersion replaces the recorded version the way golang-migrate's sqlite
+// driver does: a clean nil version is stored as an empty table. The caller
+// provides the transaction.
+func setVersion(ctx context.Context, q querier, version int64, dirty bool) error {
+	if _, err := q.ExecContext(ctx, "DELETE FROM schema_migrations"); err != nil {
+		return fmt.Errorf("clearing schema version: %w", err)
+	}
+	if version >= 0 || dirty {
+		if _, err := q.ExecContext(ctx, "INSERT INTO schema_migrations (version, dirty) VALUES (?, ?)", version, dirty); err != nil {
+			return fmt.Errorf("recording schema version: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS authors_by_created_at;
+DROP INDEX IF EXISTS authors_by_name;
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS authors_by_name ON authors (name, id);
+CREATE INDEX IF NOT EXISTS authors_by_created_at ON authors (created_at, id);
@@ -0,0 +1,27 @@
+-- Full-text index over author names and bios. It stores no text of its own:
+-- content='authors' reads it back from the authors table.
+CREATE VIRTUAL TABLE IF NOT EXISTS authors_fts USING fts5(
+	name,
+	bio,
+	content='authors',
+	content_rowid='id',
+	tokenize='unicode61 remove_diacritics 2'
+);
+
+-- An external-content index is told about removed rows by inserting their old
+-- values with the 'delete' command.
+CREATE TRIGGER IF NOT EXISTS authors_fts_insert AFTER INSERT ON authors BEGIN
+	INSERT INTO authors_fts (rowid, name, bio) VALUES (new.id, new.name, new.bio);
+END;
+
+CREATE TRIGGER IF NOT EXISTS authors_fts_delete AFTER DELETE ON authors BEGIN
+	INSERT INTO authors_fts (authors_fts, rowid, name, bio) VALUES ('delete', old.id, old.name, old.bio);
+END;
+
+CREATE TRIGGER IF NOT EXISTS authors_fts_update AFTER UPDATE OF name, bio ON authors BEGIN
+	INSERT INTO authors_fts (authors_fts, rowid, name, bio) VALUES ('delete', old.id, old.name, old.bio);
+	INSERT INTO authors_fts (rowid, name, bio) VALUES (new.id, new.name, new.bio);
+END;
+
+-- Index the authors written before this migration.
+INSERT INTO authors_fts (authors_fts) VALUES ('rebuild');
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS authors_deleted_at;
+-- Without the column, authors in the trash would come back.
+DELETE FROM authors WHERE deleted_at IS NOT NULL;
+ALTER TABLE authors DROP COLUMN deleted_at;
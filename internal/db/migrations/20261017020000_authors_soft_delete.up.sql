@@ -0,0 +1,5 @@
+-- Deleted authors are kept, with deleted_at set, until restored or purged.
+ALTER TABLE authors ADD COLUMN deleted_at DATETIME;
+
+-- The trash and its purge only look at deleted rows.
+CREATE INDEX IF NOT EXISTS authors_deleted_at ON authors (deleted_at) WHERE deleted_at IS NOT NULL;
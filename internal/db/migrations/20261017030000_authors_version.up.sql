@@ -0,0 +1,3 @@
+-- version counts the updates to an author. An update names the version it
+-- was made from and only applies while that is still current.
+ALTER TABLE authors ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
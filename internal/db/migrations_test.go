@@ -0,0 +1,147 @@
+package db_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go-htmx-template/internal/db"
+	"go-htmx-template/internal/db/queries"
+)
+
+func newTestDB(t *testing.T) db.Database {
+	t.Helper()
+	return newTestDBAt(t, filepath.Join(t.TempDir(), "db.sqlite3"))
+}
+
+func newTestDBAt(t *testing.T, path string) db.Database {
+	t.Helper()
+	database, err := db.New(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = database.Close() })
+	return database
+}
+
+func TestMigrate_UpAndDown(t *testing.T) {
+	t.Parallel()
+
+	database := newTestDB(t)
+	latest, err := db.LatestMigrationVersion()
+	require.NoError(t, err)
+
+	_, _, err = db.SchemaVersion(t.Context(), database)
+	require.ErrorIs(t, err, db.ErrNoSchemaVersion)
+
+	require.NoError(t, db.Migrate(t.Context(), database, db.Up, 0))
+	version, dirty, err := db.SchemaVersion(t.Context(), database)
+	require.NoError(t, err)
+	assert.Equal(t, latest, version)
+	assert.False(t, dirty)
+
+	_, err = database.DB().ExecContext(t.Context(), "INSERT INTO authors (name) VALUES ('a')")
+	require.NoError(t, err, "up migration should create the schema")
+
+	// Applying again is a no-op.
+	require.NoError(t, db.Migrate(t.Context(), database, db.Up, 0))
+
+	require.NoError(t, db.Migrate(t.Context(), database, db.Down, 0))
+	_, _, err = db.SchemaVersion(t.Context(), database)
+	require.ErrorIs(t, err, db.ErrNoSchemaVersion)
+	_, err = database.DB().ExecContext(t.Context(), "SELECT 1 FROM authors")
+	require.Error(t, err, "down migration should drop the schema")
+}
+
+func TestMigrate_RefusesDirtyDatabase(t *testing.T) {
+	t.Parallel()
+
+	database := newTestDB(t)
+	require.NoError(t, db.Migrate(t.Context(), database, db.Up, 0))
+	_, err := database.DB().ExecContext(t.Context(), "UPDATE schema_migrations SET dirty = 1")
+	require.NoError(t, err)
+
+	require.ErrorIs(t, db.Migrate(t.Context(), database, db.Up, 0), db.ErrDirty)
+	require.ErrorIs(t, db.Migrate(t.Context(), database, db.Down, 1), db.ErrDirty)
+}
+
+func TestMigrate_UnknownDirection(t *testing.T) {
+	t.Parallel()
+
+	require.Error(t, db.Migrate(t.Context(), newTestDB(t), "sideways", 0))
+}
+
+func TestMigrationStatus(t *testing.T) {
+	t.Parallel()
+
+	database := newTestDB(t)
+	latest, err := db.LatestMigrationVersion()
+	require.NoError(t, err)
+
+	states, err := db.MigrationStatus(t.Context(), database)
+	require.NoError(t, err)
+	require.NotEmpty(t, states)
+	for _, s := range states {
+		assert.False(t, s.Applied, "version %d", s.Version)
+	}
+
+	require.NoError(t, db.Migrate(t.Context(), database, db.Up, 0))
+	_, err = database.DB().ExecContext(t.Context(), "UPDATE schema_migrations SET dirty = 1")
+	require.NoError(t, err)
+	states, err = db.MigrationStatus(t.Context(), database)
+	require.NoError(t, err)
+	last := states[len(states)-1]
+	assert.Equal(t, latest, last.Version)
+	assert.True(t, last.Applied)
+	assert.True(t, last.Dirty)
+
+	// A version applied by a newer build is reported without a name.
+	_, err = database.DB().ExecContext(t.Context(), "UPDATE schema_migrations SET version = ?, dirty = 0", latest+1)
+	require.NoError(t, err)
+	states, err = db.MigrationStatus(t.Context(), database)
+	require.NoError(t, err)
+	assert.Equal(t, db.MigrationState{Version: latest + 1, Applied: true}, states[len(states)-1])
+}
+
+func TestPlan(t *testing.T) {
+	t.Parallel()
+
+	database := newTestDB(t)
+
+	planned, err := db.Plan(t.Context(), database, db.Up, 0)
+	require.NoError(t, err)
+	require.NotEmpty(t, planned)
+	assert.Equal(t, db.Up, planned[0].Direction)
+	assert.Contains(t, planned[0].SQL, "CREATE TABLE")
+
+	_, _, err = db.SchemaVersion(t.Context(), database)
+	require.ErrorIs(t, err, db.ErrNoSchemaVersion, "planning must not touch the database")
+
+	require.NoError(t, db.Migrate(t.Context(), database, db.Up, 0))
+	planned, err = db.Plan(t.Context(), database, db.Up, 0)
+	require.NoError(t, err)
+	assert.Empty(t, planned)
+
+	planned, err = db.Plan(t.Context(), database, db.Down, 1)
+	require.NoError(t, err)
+	require.Len(t, planned, 1)
+	assert.Contains(t, planned[0].SQL, "DROP ")
+}
+
+func TestMigrate_AuthorsSearchBackfill(t *testing.T) {
+	t.Parallel()
+
+	database := newTestDB(t)
+
+	// Up to the migration before the search index. The queries are for the
+	// latest schema, so write the author directly.
+	require.NoError(t, db.Migrate(t.Context(), database, db.Up, 3))
+	_, err := database.DB().ExecContext(t.Context(), "INSERT INTO authors (name) VALUES ('Ada Lovelace')")
+	require.NoError(t, err)
+
+	require.NoError(t, db.Migrate(t.Context(), database, db.Up, 0))
+	found, err := database.Queries().SearchAuthors(t.Context(), queries.SearchAuthorsParams{Query: "lovelace", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, found, 1, "authors written before the migration are indexed")
+	assert.Equal(t, "Ada \x02Lovelace\x03", found[0].NameHighlight)
+}
@@ -1,9 +1,13 @@
+-- Deleted authors stay in the trash, with deleted_at set, until they are
+-- restored or purged. Only the trash queries see them.
+
 -- name: GetAuthor :one
 SELECT * FROM authors
-WHERE id = ? LIMIT 1;
+WHERE id = ? AND deleted_at IS NULL LIMIT 1;
 
 -- name: ListAuthors :many
 SELECT * FROM authors
+WHERE deleted_at IS NULL
 ORDER BY name;
 
 -- name: CreateAuthor :one
@@ -14,12 +18,124 @@ INSERT INTO authors (
 )
 RETURNING *;
 
--- name: UpdateAuthor :exec
+-- UpdateAuthor only applies to the version the edit started from, and
+-- returns no rows if the author has changed since.
+
+-- name: UpdateAuthor :one
 UPDATE authors
 SET name = ?,
-bio = ?
-WHERE id = ?;
+bio = ?,
+version = version + 1
+WHERE id = ? AND version = ? AND deleted_at IS NULL
+RETURNING *;
+
+-- name: TrashAuthor :one
+UPDATE authors
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE id = ? AND deleted_at IS NULL
+RETURNING *;
+
+-- name: RestoreAuthor :one
+UPDATE authors
+SET deleted_at = NULL
+WHERE id = ? AND deleted_at IS NOT NULL
+RETURNING *;
 
--- name: DeleteAuthor :exec
+-- name: ListTrashedAuthors :many
+SELECT * FROM authors
+WHERE deleted_at IS NOT NULL
+ORDER BY deleted_at DESC, id DESC;
+
+-- name: PurgeAuthor :execrows
 DELETE FROM authors
-WHERE id = ?;
+WHERE id = ? AND deleted_at IS NOT NULL;
+
+-- name: PurgeTrashedAuthors :execrows
+DELETE FROM authors
+WHERE deleted_at < CAST(sqlc.arg(before) AS TEXT);
+
+-- name: GetCounter :one
+SELECT value FROM counters
+WHERE name = ?;
+
+-- name: IncrementCounter :one
+INSERT INTO counters (
+  name, value
+) VALUES (
+  ?, 1
+)
+ON CONFLICT (name) DO UPDATE SET value = value + 1
+RETURNING value;
+
+-- Keyset pagination: each sort order has a first-page query and one that
+-- continues after a (sort value, id) cursor, so pages stay stable while rows
+-- are inserted. A previous page is the opposite order after the first row,
+-- reversed. created_at cursors are compared as the stored text.
+
+-- name: ListAuthorsByName :many
+SELECT * FROM authors
+WHERE deleted_at IS NULL
+ORDER BY name, id
+LIMIT sqlc.arg(limit);
+
+-- name: ListAuthorsByNameAfter :many
+SELECT * FROM authors
+WHERE deleted_at IS NULL AND (name, id) > (sqlc.arg(name), CAST(sqlc.arg(id) AS INTEGER))
+ORDER BY name, id
+LIMIT sqlc.arg(limit);
+
+-- name: ListAuthorsByNameDesc :many
+SELECT * FROM authors
+WHERE deleted_at IS NULL
+ORDER BY name DESC, id DESC
+LIMIT sqlc.arg(limit);
+
+-- name: ListAuthorsByNameDescAfter :many
+SELECT * FROM authors
+WHERE deleted_at IS NULL AND (name, id) < (sqlc.arg(name), CAST(sqlc.arg(id) AS INTEGER))
+ORDER BY name DESC, id DESC
+LIMIT sqlc.arg(limit);
+
+-- name: ListAuthorsByCreated :many
+SELECT * FROM authors
+WHERE deleted_at IS NULL
+ORDER BY created_at, id
+LIMIT sqlc.arg(limit);
+
+-- name: ListAuthorsByCreatedAfter :many
+SELECT * FROM authors
+WHERE deleted_at IS NULL AND (created_at, id) > (CAST(sqlc.arg(created_at) AS TEXT), CAST(sqlc.arg(id) AS INTEGER))
+ORDER BY created_at, id
+LIMIT sqlc.arg(limit);
+
+-- name: ListAuthorsByCreatedDesc :many
+SELECT * FROM authors
+WHERE deleted_at IS NULL
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(limit);
+
+-- name: ListAuthorsByCreatedDescAfter :many
+SELECT * FROM authors
+WHERE deleted_at IS NULL AND (created_at, id) < (CAST(sqlc.arg(created_at) AS TEXT), CAST(sqlc.arg(id) AS INTEGER))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(limit);
+
+-- Search ranks name matches above bio matches. The highlighted name and the
+-- bio snippet bracket each match with char(2) and char(3), so the text can
+-- still be escaped when it is rendered. The match string is cast to TEXT
+-- because sqlc does not know the hidden column named after an FTS5 table and
+-- rejects a bare parameter compared with it.
+
+-- name: SearchAuthors :many
+SELECT
+  authors.id,
+  authors.created_at,
+  authors.name,
+  authors.bio,
+  CAST(highlight(authors_fts, 0, char(2), char(3)) AS TEXT) AS name_highlight,
+  CAST(coalesce(snippet(authors_fts, 1, char(2), char(3), '...', 12), '') AS TEXT) AS bio_snippet
+FROM authors_fts
+JOIN authors ON authors.id = authors_fts.rowid
+WHERE authors_fts MATCH CAST(sqlc.arg(query) AS TEXT) AND authors.deleted_at IS NULL
+ORDER BY bm25(authors_fts, 10.0, 1.0), authors.id
+LIMIT sqlc.arg(limit);
@@ -0,0 +1,138 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+)
+
+// DriftKind is how a schema object differs from the embedded migrations.
+type DriftKind string
+
+const (
+	// DriftMissing is an object the migrations create that the database lacks.
+	DriftMissing DriftKind = "missing"
+	// DriftUnexpected is an object in the database the migrations do not create.
+	DriftUnexpected DriftKind = "unexpected"
+	// DriftChanged is an object whose definition differs from the migrations.
+	DriftChanged DriftKind = "changed"
+)
+
+// Drift is one difference between the live schema and the embedded migrations.
+type Drift struct {
+	Kind DriftKind
+	// Type is table, index, view or trigger.
+	Type string
+	Name string
+	// Want and Got are the normalized CREATE statements; Want is empty for
+	// unexpected objects and Got for missing ones.
+	Want string
+	Got  string
+}
+
+func (d Drift) String() string {
+	return fmt.Sprintf("%s %s %s", d.Kind, d.Type, d.Name)
+}
+
+// SchemaDrift compares the live schema with the one the embedded up migrations
+// produce on an empty database, which is the schema sqlc generated
+// internal/db/queries from. It returns nil when they match.
+func SchemaDrift(ctx context.Context, database Database) ([]Drift, error) {
+	return schemaDrift(ctx, database.ReadDB(), migrationFiles)
+}
+
+func schemaDrift(ctx context.Context, q querier, fsys fs.FS) ([]Drift, error) {
+	want, err := expectedSchema(ctx, fsys)
+	if err != nil {
+		return nil, err
+	}
+	got, err := readSchema(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	var drift []Drift
+	for key, w := range want {
+		g, ok := got[key]
+		switch {
+		case !ok:
+			drift = append(drift, Drift{Kind: DriftMissing, Type: key.typ, Name: key.name, Want: w})
+		case g != w:
+			drift = append(drift, Drift{Kind: DriftChanged, Type: key.typ, Name: key.name, Want: w, Got: g})
+		}
+	}
+	for key, g := range got {
+		if _, ok := want[key]; !ok {
+			drift = append(drift, Drift{Kind: DriftUnexpected, Type: key.typ, Name: key.name, Got: g})
+		}
+	}
+	slices.SortFunc(drift, func(a, b Drift) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.Type, b.Type))
+	})
+	return drift, nil
+}
+
+type schemaKey struct {
+	typ  string
+	name string
+}
+
+// expectedSchema applies every up migration in fsys to an in-memory database
+// and reads back its schema.
+func expectedSchema(ctx context.Context, fsys fs.FS) (map[schemaKey]string, error) {
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	mem, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		return nil, fmt.Errorf("opening scratch database: %w", err)
+	}
+	defer mem.Close()
+	// Each connection to :memory: is a separate database.
+	mem.SetMaxOpenConns(1)
+
+	for _, m := range migrations {
+		if m.up == "" {
+			return nil, fmt.Errorf("%w: up %d_%s", errMissingFile, m.version, m.name)
+		}
+		script, rerr := fs.ReadFile(fsys, m.up)
+		if rerr != nil {
+			return nil, fmt.Errorf("reading %s: %w", m.up, rerr)
+		}
+		if _, err = mem.ExecContext(ctx, string(script)); err != nil {
+			return nil, fmt.Errorf("applying %s to scratch database: %w", m.up, err)
+		}
+	}
+	return readSchema(ctx, mem)
+}
+
+// readSchema returns the normalized CREATE statement of every user object,
+// leaving out SQLite's internal objects and golang-migrate's version table.
+func readSchema(ctx context.Context, q querier) (map[schemaKey]string, error) {
+	rows, err := q.QueryContext(ctx, `SELECT type, name, sql FROM sqlite_schema
+WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite\_%' ESCAPE '\' AND tbl_name != 'schema_migrations'`)
+	if err != nil {
+		return nil, fmt.Errorf("reading schema: %w", err)
+	}
+	defer rows.Close()
+
+	schema := make(map[schemaKey]string)
+	for rows.Next() {
+		var key schemaKey
+		var stmt string
+		if err = rows.Scan(&key.typ, &key.name, &stmt); err != nil {
+			return nil, fmt.Errorf("reading schema: %w", err)
+		}
+		schema[key] = strings.Join(strings.Fields(stmt), " ")
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading schema: %w", err)
+	}
+	return schema, nil
+}
@@ -0,0 +1,39 @@
+package db_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go-htmx-template/internal/db"
+)
+
+func TestSchemaDrift(t *testing.T) {
+	t.Parallel()
+
+	database := newTestDB(t)
+
+	drift, err := db.SchemaDrift(t.Context(), database)
+	require.NoError(t, err)
+	require.NotEmpty(t, drift)
+	assert.Equal(t, "missing table authors", drift[0].String())
+	for _, d := range drift {
+		assert.Equal(t, db.DriftMissing, d.Kind, d.String())
+	}
+
+	require.NoError(t, db.Migrate(t.Context(), database, db.Up, 0))
+	drift, err = db.SchemaDrift(t.Context(), database)
+	require.NoError(t, err)
+	assert.Empty(t, drift, "a migrated database matches, ignoring schema_migrations")
+
+	_, err = database.DB().ExecContext(t.Context(),
+		"ALTER TABLE authors ADD COLUMN email TEXT; CREATE INDEX authors_name ON authors (name);")
+	require.NoError(t, err)
+	drift, err = db.SchemaDrift(t.Context(), database)
+	require.NoError(t, err)
+	require.Len(t, drift, 2)
+	assert.Equal(t, "changed table authors", drift[0].String())
+	assert.Contains(t, drift[0].Got, "email TEXT")
+	assert.Equal(t, "unexpected index authors_name", drift[1].String())
+}
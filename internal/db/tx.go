@@ -0,0 +1,140 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"time"
+
+	"go-htmx-template/internal/db/queries"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const (
+	// maxTxAttempts bounds how often InTx runs a transaction that keeps
+	// failing with SQLITE_BUSY or SQLITE_LOCKED.
+	maxTxAttempts = 5
+	// txBackoff is the delay before the first retry; it doubles each time.
+	txBackoff = 20 * time.Millisecond
+)
+
+// TxFunc is the body of a transaction. ctx carries the transaction, so calling
+// InTx with it, or a context derived from it, nests in a savepoint.
+type TxFunc func(ctx context.Context, q *queries.Queries) error
+
+type txKey struct{}
+
+// txState is the transaction open in a context and its savepoint depth.
+type txState struct {
+	tx      *sql.Tx
+	queries *queries.Queries
+	depth   int
+}
+
+// InTx runs fn in a transaction, committing if it returns nil and rolling
+// back if it returns an error or panics.
+//
+// Read-only transactions (opts.ReadOnly) run on the reader pool, the rest on
+// the writer. If the transaction fails with SQLITE_BUSY or SQLITE_LOCKED
+// after busy_timeout, it is retried from the start with exponential backoff,
+// so fn must not have side effects outside the database. Called with a
+// context from an enclosing InTx, it runs fn in a savepoint of that
+// transaction instead, ignoring opts; an error then rolls back only the
+// savepoint, and retrying is left to the outermost call.
+func (d *localDB) InTx(ctx context.Context, opts *sql.TxOptions, fn TxFunc) error {
+	if outer, ok := ctx.Value(txKey{}).(*txState); ok {
+		return outer.savepoint(ctx, fn)
+	}
+
+	pool := d.writer
+	if opts != nil && opts.ReadOnly {
+		pool = d.reader
+	}
+
+	delay := txBackoff
+	for attempt := 1; ; attempt++ {
+		err := d.runTx(ctx, pool, opts, fn)
+		if attempt == maxTxAttempts || !isBusy(err) {
+			return err
+		}
+
+		// Jitter keeps retrying writers from colliding again.
+		timer := time.NewTimer(delay/2 + rand.N(delay)) //nolint:gosec // jitter needs no cryptographic randomness
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+func (d *localDB) runTx(ctx context.Context, pool *sql.DB, opts *sql.TxOptions, fn TxFunc) error {
+	tx, err := pool.BeginTx(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	state := &txState{tx: tx, queries: d.writeQueries.WithTx(tx)}
+	if err = fn(context.WithValue(ctx, txKey{}, state), state.queries); err != nil {
+		if rerr := tx.Rollback(); rerr != nil {
+			return errors.Join(err, fmt.Errorf("rolling back transaction: %w", rerr))
+		}
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+	return nil
+}
+
+func (s *txState) savepoint(ctx context.Context, fn TxFunc) error {
+	inner := &txState{tx: s.tx, queries: s.queries, depth: s.depth + 1}
+	name := "tx_" + strconv.Itoa(inner.depth)
+	if _, err := s.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("starting savepoint: %w", err)
+	}
+	rollback := func() error {
+		if _, err := s.tx.ExecContext(context.WithoutCancel(ctx), "ROLLBACK TO "+name+"; RELEASE "+name); err != nil {
+			return fmt.Errorf("rolling back savepoint: %w", err)
+		}
+		return nil
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, inner), inner.queries); err != nil {
+		return errors.Join(err, rollback())
+	}
+	if _, err := s.tx.ExecContext(ctx, "RELEASE "+name); err != nil {
+		return fmt.Errorf("releasing savepoint: %w", err)
+	}
+	return nil
+}
+
+// isBusy reports whether err is SQLITE_BUSY or SQLITE_LOCKED, including their
+// extended codes.
+func isBusy(err error) bool {
+	var serr *sqlite.Error
+	if !errors.As(err, &serr) {
+		return false
+	}
+	code := serr.Code() & 0xff
+	return code == sqlite3.SQLITE_BUSY || code == sqlite3.SQLITE_LOCKED
+}
@@ -0,0 +1,136 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go-htmx-template/internal/db"
+	"go-htmx-template/internal/db/queries"
+)
+
+var errAbort = errors.New("abort")
+
+func countAuthors(t *testing.T, database db.Database) int {
+	t.Helper()
+	authors, err := database.ReadQueries().ListAuthors(t.Context())
+	require.NoError(t, err)
+	return len(authors)
+}
+
+func createAuthor(ctx context.Context, q *queries.Queries, name string) error {
+	_, err := q.CreateAuthor(ctx, queries.CreateAuthorParams{Name: name})
+	return err
+}
+
+func TestInTx_CommitsAndRollsBack(t *testing.T) {
+	t.Parallel()
+
+	database := newMigratedDB(t, newTestDB(t))
+
+	require.NoError(t, database.InTx(t.Context(), nil, func(ctx context.Context, q *queries.Queries) error {
+		return createAuthor(ctx, q, "kept")
+	}))
+	assert.Equal(t, 1, countAuthors(t, database))
+
+	err := database.InTx(t.Context(), nil, func(ctx context.Context, q *queries.Queries) error {
+		require.NoError(t, createAuthor(ctx, q, "discarded"))
+		return errAbort
+	})
+	require.ErrorIs(t, err, errAbort)
+	assert.Equal(t, 1, countAuthors(t, database))
+
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = database.InTx(t.Context(), nil, func(ctx context.Context, q *queries.Queries) error {
+			require.NoError(t, createAuthor(ctx, q, "discarded"))
+			panic("boom")
+		})
+	})
+	assert.Equal(t, 1, countAuthors(t, database))
+
+	// The writer's connection was released despite the panic.
+	require.NoError(t, database.InTx(t.Context(), nil, func(ctx context.Context, q *queries.Queries) error {
+		return createAuthor(ctx, q, "after panic")
+	}))
+	assert.Equal(t, 2, countAuthors(t, database))
+}
+
+func TestInTx_NestsInSavepoints(t *testing.T) {
+	t.Parallel()
+
+	database := newMigratedDB(t, newTestDB(t))
+
+	require.NoError(t, database.InTx(t.Context(), nil, func(ctx context.Context, q *queries.Queries) error {
+		require.NoError(t, createAuthor(ctx, q, "outer"))
+
+		err := database.InTx(ctx, nil, func(ctx context.Context, q *queries.Queries) error {
+			require.NoError(t, createAuthor(ctx, q, "inner"))
+			return database.InTx(ctx, nil, func(ctx context.Context, q *queries.Queries) error {
+				return createAuthor(ctx, q, "innermost")
+			})
+		})
+		require.NoError(t, err)
+
+		err = database.InTx(ctx, nil, func(ctx context.Context, q *queries.Queries) error {
+			require.NoError(t, createAuthor(ctx, q, "rolled back"))
+			return errAbort
+		})
+		require.ErrorIs(t, err, errAbort)
+		return nil
+	}))
+
+	authors, err := database.ReadQueries().ListAuthors(t.Context())
+	require.NoError(t, err)
+	names := make([]string, 0, len(authors))
+	for _, a := range authors {
+		names = append(names, a.Name)
+	}
+	assert.ElementsMatch(t, []string{"outer", "inner", "innermost"}, names)
+}
+
+func TestInTx_ReadOnlyUsesReaders(t *testing.T) {
+	t.Parallel()
+
+	database := newMigratedDB(t, newTestDB(t))
+
+	err := database.InTx(t.Context(), &sql.TxOptions{ReadOnly: true}, func(ctx context.Context, q *queries.Queries) error {
+		return createAuthor(ctx, q, "a")
+	})
+	require.Error(t, err)
+	assert.Equal(t, 0, countAuthors(t, database))
+}
+
+func TestInTx_RetriesWhenBusy(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "db.sqlite3")
+	newMigratedDB(t, newTestDBAt(t, path))
+
+	// Without busy_timeout, SQLite reports SQLITE_BUSY at once and InTx's
+	// own retries have to wait out the lock.
+	raw, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(0)&_txlock=immediate")
+	require.NoError(t, err)
+	database := db.NewFromRawDB(raw)
+	t.Cleanup(func() { _ = database.Close() })
+
+	lock, err := newTestDBAt(t, path).DB().Conn(t.Context())
+	require.NoError(t, err)
+	defer lock.Close()
+	_, err = lock.ExecContext(t.Context(), "BEGIN IMMEDIATE")
+	require.NoError(t, err)
+	time.AfterFunc(50*time.Millisecond, func() { _, _ = lock.ExecContext(context.Background(), "COMMIT") })
+
+	calls := 0
+	require.NoError(t, database.InTx(t.Context(), nil, func(ctx context.Context, q *queries.Queries) error {
+		calls++
+		return createAuthor(ctx, q, "a")
+	}))
+	assert.Equal(t, 1, calls, "BEGIN IMMEDIATE fails before fn runs")
+	assert.Equal(t, 1, countAuthors(t, database))
+}
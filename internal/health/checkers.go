@@ -0,0 +1,65 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go-htmx-template/internal/db"
+)
+
+var (
+	errSchemaDirty    = errors.New("schema is dirty; a migration failed part way")
+	errSchemaOutdated = errors.New("schema version does not match migrations")
+	errLowDiskSpace   = errors.New("low disk space")
+)
+
+// Ping checks that the database accepts connections.
+func Ping(database db.Database) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		if err := database.ReadDB().PingContext(ctx); err != nil {
+			return fmt.Errorf("pinging database: %w", err)
+		}
+		return nil
+	})
+}
+
+// Migrations checks that the database schema is at the latest embedded
+// migration version and is not dirty.
+func Migrations(database db.Database) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		want, err := db.LatestMigrationVersion()
+		if err != nil {
+			return err
+		}
+		got, dirty, err := db.SchemaVersion(ctx, database)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("%w: version %d", errSchemaDirty, got)
+		}
+		if got != want {
+			return fmt.Errorf("%w: at %d, want %d", errSchemaOutdated, got, want)
+		}
+		return nil
+	})
+}
+
+// DiskSpace checks that the filesystem holding path has at least minFree bytes
+// available. It always passes on platforms without filesystem statistics.
+func DiskSpace(path string, minFree uint64) Checker {
+	return CheckerFunc(func(context.Context) error {
+		free, err := freeBytes(path)
+		if errors.Is(err, errDiskStatsUnsupported) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if free < minFree {
+			return fmt.Errorf("%w: %d bytes free, want at least %d", errLowDiskSpace, free, minFree)
+		}
+		return nil
+	})
+}
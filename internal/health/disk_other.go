@@ -0,0 +1,11 @@
+//go:build !unix
+
+package health
+
+import "errors"
+
+var errDiskStatsUnsupported = errors.New("disk statistics are not supported on this platform")
+
+func freeBytes(string) (uint64, error) {
+	return 0, errDiskStatsUnsupported
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package health
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+var errDiskStatsUnsupported = errors.New("disk statistics are not supported on this platform")
+
+// freeBytes returns the bytes available to unprivileged users on the
+// filesystem holding path.
+func freeBytes(path string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, fmt.Errorf("statfs %s: %w", path, err)
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil //nolint:gosec,unconvert // field types vary by platform
+}
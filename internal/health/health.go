@@ -0,0 +1,160 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultTimeout is the per-check timeout used when Register is given zero.
+const DefaultTimeout = 2 * time.Second
+
+// Checker reports whether a dependency is usable. Check should return promptly
+// once ctx is done.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to a Checker.
+type CheckerFunc func(ctx context.Context) error
+
+// Check implements Checker.
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+const (
+	// StatusOK means every check passed.
+	StatusOK = "ok"
+	// StatusError means at least one check failed.
+	StatusError = "error"
+	// StatusDraining means the server is shutting down and should receive no
+	// new traffic.
+	StatusDraining = "draining"
+)
+
+// Readiness is a registry of checkers that together decide whether the server
+// should receive traffic.
+type Readiness struct {
+	mu       sync.RWMutex
+	checks   []check
+	draining atomic.Bool
+}
+
+type check struct {
+	name    string
+	checker Checker
+	timeout time.Duration
+}
+
+// NewReadiness creates an empty registry, which reports ready until a checker
+// is registered.
+func NewReadiness() *Readiness {
+	return &Readiness{}
+}
+
+// Register adds a named checker. A zero timeout uses DefaultTimeout.
+func (r *Readiness) Register(name string, checker Checker, timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checks = append(r.checks, check{name: name, checker: checker, timeout: timeout})
+}
+
+// SetDraining marks the server as shutting down. From then on every report is
+// StatusDraining without running the checks.
+func (r *Readiness) SetDraining() {
+	r.draining.Store(true)
+}
+
+// Report is the outcome of running every registered check.
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// CheckResult is the outcome of a single check.
+type CheckResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration,omitempty"`
+}
+
+// Redacted returns the report with only the status of each check, leaving out
+// errors and timings that could reveal internals to the public.
+func (r Report) Redacted() Report {
+	if r.Checks == nil {
+		return r
+	}
+	checks := make(map[string]CheckResult, len(r.Checks))
+	for name, result := range r.Checks {
+		checks[name] = CheckResult{Status: result.Status}
+	}
+	return Report{Status: r.Status, Checks: checks}
+}
+
+// Ready reports whether the report allows traffic.
+func (r Report) Ready() bool {
+	return r.Status == StatusOK
+}
+
+// Check runs every registered checker concurrently, each bounded by its own
+// timeout.
+func (r *Readiness) Check(ctx context.Context) Report {
+	if r.draining.Load() {
+		return Report{Status: StatusDraining}
+	}
+
+	r.mu.RLock()
+	checks := append([]check(nil), r.checks...)
+	r.mu.RUnlock()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Go(func() {
+			results[i] = runCheck(ctx, c)
+		})
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(checks))}
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusError
+		}
+	}
+	return report
+}
+
+// runCheck runs one check, giving up when its timeout expires even if the
+// checker ignores ctx.
+func runCheck(ctx context.Context, c check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- c.checker.Check(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s: %w", c.timeout, ctx.Err())
+	}
+
+	result := CheckResult{Status: StatusOK, Duration: time.Since(start).Round(time.Microsecond).String()}
+	if err != nil {
+		result.Status = StatusError
+		result.Error = err.Error()
+	}
+	return result
+}
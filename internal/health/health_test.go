@@ -0,0 +1,104 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go-htmx-template/internal/health"
+)
+
+func passing() health.Checker {
+	return health.CheckerFunc(func(context.Context) error { return nil })
+}
+
+func TestReadiness_Check(t *testing.T) {
+	t.Parallel()
+
+	blocked := make(chan struct{})
+	t.Cleanup(func() { close(blocked) })
+
+	tests := []struct {
+		name     string
+		checker  health.Checker
+		timeout  time.Duration
+		expected string
+		errMsg   string
+	}{
+		{name: "passing check", checker: passing(), expected: health.StatusOK},
+		{
+			name:     "failing check",
+			checker:  health.CheckerFunc(func(context.Context) error { return errors.New("boom") }),
+			expected: health.StatusError,
+			errMsg:   "boom",
+		},
+		{
+			name: "check honoring the timeout",
+			checker: health.CheckerFunc(func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}),
+			timeout:  10 * time.Millisecond,
+			expected: health.StatusError,
+			errMsg:   "timed out",
+		},
+		{
+			name: "check ignoring the timeout",
+			checker: health.CheckerFunc(func(context.Context) error {
+				<-blocked
+				return nil
+			}),
+			timeout:  10 * time.Millisecond,
+			expected: health.StatusError,
+			errMsg:   "timed out",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			readiness := health.NewReadiness()
+			readiness.Register("ok", passing(), 0)
+			readiness.Register("subject", tt.checker, tt.timeout)
+
+			report := readiness.Check(t.Context())
+			assert.Equal(t, tt.expected, report.Status)
+			assert.Equal(t, tt.expected == health.StatusOK, report.Ready())
+			require.Contains(t, report.Checks, "subject")
+			assert.Equal(t, health.StatusOK, report.Checks["ok"].Status)
+			assert.Equal(t, tt.expected, report.Checks["subject"].Status)
+			assert.Contains(t, report.Checks["subject"].Error, tt.errMsg)
+		})
+	}
+}
+
+func TestReadiness_Draining(t *testing.T) {
+	t.Parallel()
+
+	called := false
+	readiness := health.NewReadiness()
+	readiness.Register("db", health.CheckerFunc(func(context.Context) error {
+		called = true
+		return nil
+	}), 0)
+	require.True(t, readiness.Check(t.Context()).Ready())
+
+	called = false
+	readiness.SetDraining()
+	report := readiness.Check(t.Context())
+	assert.False(t, report.Ready())
+	assert.Equal(t, health.StatusDraining, report.Status)
+	assert.False(t, called)
+}
+
+func TestDiskSpace(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, health.DiskSpace(t.TempDir(), 0).Check(t.Context()))
+	require.Error(t, health.DiskSpace(t.TempDir(), 1<<62).Check(t.Context()))
+}
@@ -1,20 +1,29 @@
 package log
 
 import (
+	"errors"
+	"fmt"
 	"log/slog"
 	"os"
+	"strings"
 )
 
-// New creates a new logger with the given level and output.
-func New(level Level, output Output) *slog.Logger {
+var (
+	errUnknownLevel  = errors.New("unknown log level")
+	errUnknownOutput = errors.New("unknown log output")
+)
+
+// New creates a new logger with the given level and output. Pass a
+// *slog.LevelVar as the level to change it while the logger is in use.
+func New(level slog.Leveler, output Output) *slog.Logger {
 	var h slog.Handler
 	switch output {
 	case OutputJSON:
-		h = slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level.ToSlog()})
+		h = slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})
 	case OutputText:
 		fallthrough
 	default:
-		h = slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: level.ToSlog()})
+		h = slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: level})
 	}
 	return slog.New(h)
 }
@@ -22,6 +31,11 @@ func New(level Level, output Output) *slog.Logger {
 // Level represents the log level.
 type Level string
 
+// Level implements slog.Leveler.
+func (l Level) Level() slog.Level {
+	return l.ToSlog()
+}
+
 // ToSlog converts the level to slog.Level.
 func (l Level) ToSlog() slog.Level {
 	switch l {
@@ -49,27 +63,30 @@ const (
 	LevelError Level = "error"
 )
 
-func toLevel(level string) Level {
-	switch level {
-	case "debug":
+// ParseLevel parses a level name, ignoring case.
+func ParseLevel(level string) (Level, error) {
+	switch l := Level(strings.ToLower(strings.TrimSpace(level))); l {
+	case LevelDebug, LevelInfo, LevelWarn, LevelError:
+		return l, nil
+	default:
+		return "", fmt.Errorf("%w: %q", errUnknownLevel, level)
+	}
+}
+
+// FromSlog converts a slog.Level to the nearest Level.
+func FromSlog(level slog.Level) Level {
+	switch {
+	case level < slog.LevelInfo:
 		return LevelDebug
-	case "info":
+	case level < slog.LevelWarn:
 		return LevelInfo
-	case "warn":
+	case level < slog.LevelError:
 		return LevelWarn
-	case "error":
-		return LevelError
 	default:
-		return LevelInfo
+		return LevelError
 	}
 }
 
-// GetLevel returns the log level from the environment variable.
-func GetLevel() Level {
-	level := os.Getenv("LOG_LEVEL")
-	return toLevel(level)
-}
-
 // Output represents the log output.
 type Output string
 
@@ -80,19 +97,12 @@ const (
 	OutputText Output = "text"
 )
 
-func toOutput(output string) Output {
-	switch output {
-	case "json":
-		return OutputJSON
-	case "text":
-		return OutputText
+// ParseOutput parses an output name, ignoring case.
+func ParseOutput(output string) (Output, error) {
+	switch o := Output(strings.ToLower(strings.TrimSpace(output))); o {
+	case OutputJSON, OutputText:
+		return o, nil
 	default:
-		return OutputText
+		return "", fmt.Errorf("%w: %q", errUnknownOutput, output)
 	}
 }
-
-// GetOutput returns the log output from the environment variable.
-func GetOutput() Output {
-	output := os.Getenv("LOG_OUTPUT")
-	return toOutput(output)
-}
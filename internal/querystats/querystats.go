@@ -0,0 +1,55 @@
+// Package querystats counts the database queries run with a context. It has no
+// dependencies so that both the database layer, which records queries, and
+// the HTTP middleware, which reports them per request, can import it.
+package querystats
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Stats counts the queries run with a context from NewContext and the time
+// spent in them. It is safe for concurrent use.
+type Stats struct {
+	requestID string
+	count     atomic.Int64
+	duration  atomic.Int64
+}
+
+// RequestID is the ID of the request the queries were run for, if any.
+func (s *Stats) RequestID() string {
+	return s.requestID
+}
+
+// Count is the number of queries run so far.
+func (s *Stats) Count() int {
+	return int(s.count.Load())
+}
+
+// Duration is the total time spent in those queries, including reading their
+// rows.
+func (s *Stats) Duration() time.Duration {
+	return time.Duration(s.duration.Load())
+}
+
+// Add records a query that took d.
+func (s *Stats) Add(d time.Duration) {
+	s.count.Add(1)
+	s.duration.Add(int64(d))
+}
+
+type contextKey struct{}
+
+// NewContext returns a context that records the queries run with it in the
+// returned Stats, which tag them with requestID.
+func NewContext(ctx context.Context, requestID string) (context.Context, *Stats) {
+	stats := &Stats{requestID: requestID}
+	return context.WithValue(ctx, contextKey{}, stats), stats
+}
+
+// FromContext returns the Stats of ctx, or nil if it has none.
+func FromContext(ctx context.Context) *Stats {
+	stats, _ := ctx.Value(contextKey{}).(*Stats)
+	return stats
+}
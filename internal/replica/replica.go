@@ -0,0 +1,224 @@
+// Package replica continuously copies a SQLite database in WAL mode to a
+// Store, so it can be restored to any point in time the store covers.
+package replica
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"time"
+
+	"go-htmx-template/internal/db"
+)
+
+// checkpointSize is the WAL size past which the replicator checkpoints it
+// into the database file, about SQLite's default of 1000 pages.
+const checkpointSize = 4 << 20
+
+var errContinuityLost = errors.New("WAL was reset before all of it was shipped")
+
+// Replicator ships a database's WAL to a Store as it grows.
+//
+// It holds the database's single writer connection while it reads the WAL,
+// so nothing in the process commits or checkpoints meanwhile, and it turns off
+// automatic checkpoints on that connection so the WAL is only reset after it
+// has been shipped. If another process resets the WAL first, the replicator
+// starts a new generation.
+type Replicator struct {
+	logger           *slog.Logger
+	database         db.Database
+	path             string
+	store            Store
+	snapshotInterval time.Duration
+	retention        time.Duration
+
+	// The position in the current generation.
+	generation time.Time
+	next       uint64
+	// salt identifies the WAL being shipped; hasSalt is false until its
+	// header has been read.
+	salt    [8]byte
+	hasSalt bool
+	// offset is how far the WAL has been shipped.
+	offset int64
+	// checkpointed is the offset up to which the WAL was also checkpointed
+	// into the database file, or -1. A WAL restart is expected only when it
+	// equals offset.
+	checkpointed int64
+}
+
+// New returns a Replicator for database, whose file is at path. It starts a
+// new generation every snapshotInterval and deletes generations no longer
+// needed to restore to any time within retention.
+func New(
+	logger *slog.Logger,
+	database db.Database,
+	path string,
+	store Store,
+	snapshotInterval, retention time.Duration,
+) *Replicator {
+	return &Replicator{
+		logger:           logger,
+		database:         database,
+		path:             path,
+		store:            store,
+		snapshotInterval: snapshotInterval,
+		retention:        retention,
+		checkpointed:     -1,
+	}
+}
+
+// Run syncs every interval until ctx is done, then syncs once more so writes
+// made during shutdown are shipped too.
+func (r *Replicator) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Sync(ctx); err != nil && ctx.Err() == nil {
+			r.logger.Error("replication failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			if err := r.Sync(context.WithoutCancel(ctx)); err != nil {
+				r.logger.Error("final replication failed", "error", err)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync ships the WAL written since the last call, starting a new generation
+// first if the current one is due for a snapshot, and checkpoints the WAL
+// once it grows past checkpointSize.
+func (r *Replicator) Sync(ctx context.Context) error {
+	conn, err := r.database.DB().Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("acquiring writer: %w", err)
+	}
+	defer conn.Close()
+	if _, err = conn.ExecContext(ctx, "PRAGMA wal_autocheckpoint=0"); err != nil {
+		return fmt.Errorf("disabling automatic checkpoints: %w", err)
+	}
+
+	now := time.Now().UTC()
+	if r.generation.IsZero() || now.Sub(r.generation) >= r.snapshotInterval {
+		if err = r.snapshot(ctx, now); err != nil {
+			return err
+		}
+	}
+
+	err = r.ship(ctx, now)
+	if errors.Is(err, errContinuityLost) {
+		r.logger.Warn("WAL reset by another connection; starting a new generation")
+		if err = r.snapshot(ctx, now); err != nil {
+			return err
+		}
+		err = r.ship(ctx, now)
+	}
+	if err != nil {
+		return err
+	}
+
+	if r.offset < checkpointSize {
+		return nil
+	}
+	var busy, walFrames, checkpointed int
+	err = conn.QueryRowContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)").Scan(&busy, &walFrames, &checkpointed)
+	if err != nil {
+		return fmt.Errorf("checkpointing: %w", err)
+	}
+	// Even when readers kept the WAL from being truncated, every frame in it
+	// is in the database file, so the next writer may restart it.
+	if busy == 0 || walFrames == checkpointed {
+		r.checkpointed = r.offset
+	}
+	return nil
+}
+
+// snapshot starts a generation with a copy of the database file. The WAL
+// frames not yet checkpointed into it follow as the generation's segments.
+func (r *Replicator) snapshot(ctx context.Context, now time.Time) error {
+	f, err := os.Open(r.path)
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer f.Close()
+	if err = r.store.PutSnapshot(ctx, now, f); err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+
+	r.generation, r.next = now, 1
+	r.hasSalt, r.offset, r.checkpointed = false, 0, -1
+	r.logger.Info("replica generation started", "generation", now)
+	r.prune(ctx, now)
+	return nil
+}
+
+// ship writes the committed WAL frames past offset as the next segment.
+func (r *Replicator) ship(ctx context.Context, now time.Time) error {
+	wal, err := os.ReadFile(r.path + "-wal")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("reading WAL: %w", err)
+	}
+
+	var header walHeader
+	if len(wal) >= walHeaderSize {
+		if header, err = parseWALHeader(wal); err != nil {
+			return err
+		}
+	}
+	restarted := r.hasSalt && (len(wal) < walHeaderSize || int64(len(wal)) < r.offset || header.salt != r.salt)
+	if restarted {
+		if r.checkpointed != r.offset {
+			return errContinuityLost
+		}
+		r.hasSalt, r.offset, r.checkpointed = false, 0, -1
+	}
+	if len(wal) < walHeaderSize {
+		return nil
+	}
+	if !r.hasSalt {
+		r.salt, r.hasSalt, r.offset = header.salt, true, walHeaderSize
+	}
+
+	n := committedLength(wal[r.offset:], header.pageSize, r.salt)
+	if n == 0 {
+		return nil
+	}
+	seg := Segment{Index: r.next, Shipped: now}
+	if err = r.store.PutSegment(ctx, r.generation, seg, bytes.NewReader(wal[r.offset:r.offset+int64(n)])); err != nil {
+		return fmt.Errorf("writing segment: %w", err)
+	}
+	r.next++
+	r.offset += int64(n)
+	return nil
+}
+
+// prune deletes the generations before the newest one that started at or
+// before now minus retention; that one is still needed to restore to the start
+// of the window.
+func (r *Replicator) prune(ctx context.Context, now time.Time) {
+	generations, err := r.store.Generations(ctx)
+	if err != nil {
+		r.logger.Error("listing replica generations failed", "error", err)
+		return
+	}
+	cutoff := now.Add(-r.retention)
+	keepFrom := 0
+	for i, generation := range generations {
+		if !generation.After(cutoff) {
+			keepFrom = i
+		}
+	}
+	for _, generation := range generations[:keepFrom] {
+		if err = r.store.DeleteGeneration(ctx, generation); err != nil {
+			r.logger.Error("deleting replica generation failed", "generation", generation, "error", err)
+		}
+	}
+}
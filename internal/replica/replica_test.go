@@ -0,0 +1,144 @@
+package replica_test
+
+import (
+	"database/sql"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go-htmx-template/internal/db"
+	"go-htmx-template/internal/db/queries"
+	"go-htmx-template/internal/replica"
+)
+
+type fixture struct {
+	path       string
+	database   db.Database
+	store      *replica.DirStore
+	replicator *replica.Replicator
+}
+
+func newFixture(t *testing.T) fixture {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "db.sqlite3")
+	database, err := db.New(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = database.Close() })
+	require.NoError(t, db.Migrate(t.Context(), database, db.Up, 0))
+
+	store := replica.NewDirStore(filepath.Join(dir, "replica"))
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return fixture{
+		path:       path,
+		database:   database,
+		store:      store,
+		replicator: replica.New(logger, database, path, store, time.Hour, time.Hour),
+	}
+}
+
+func (f fixture) insert(t *testing.T, name, bio string) {
+	t.Helper()
+	_, err := f.database.Queries().CreateAuthor(t.Context(), queries.CreateAuthorParams{
+		Name: name,
+		Bio:  sql.NullString{String: bio, Valid: bio != ""},
+	})
+	require.NoError(t, err)
+}
+
+func (f fixture) sync(t *testing.T) time.Time {
+	t.Helper()
+	require.NoError(t, f.replicator.Sync(t.Context()))
+	// Segments are stamped with the time of the sync; step past it.
+	time.Sleep(2 * time.Millisecond)
+	return time.Now()
+}
+
+func restoredNames(t *testing.T, store replica.Store, at time.Time) []string {
+	t.Helper()
+	dest := filepath.Join(t.TempDir(), "restored.sqlite3")
+	_, err := replica.Restore(t.Context(), store, dest, at)
+	require.NoError(t, err)
+	require.NoError(t, db.VerifyBackup(t.Context(), dest))
+
+	restored, err := db.New(dest)
+	require.NoError(t, err)
+	defer restored.Close()
+	authors, err := restored.ReadQueries().ListAuthors(t.Context())
+	require.NoError(t, err)
+	names := make([]string, 0, len(authors))
+	for _, a := range authors {
+		names = append(names, a.Name)
+	}
+	return names
+}
+
+func TestRestore_ToTime(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t)
+	beforeReplication := time.Now().Add(-time.Millisecond)
+	empty := f.sync(t)
+
+	f.insert(t, "a", "")
+	afterA := f.sync(t)
+	f.insert(t, "b", "")
+	f.insert(t, "c", "")
+	afterC := f.sync(t)
+
+	assert.Empty(t, restoredNames(t, f.store, empty))
+	assert.Equal(t, []string{"a"}, restoredNames(t, f.store, afterA))
+	assert.Equal(t, []string{"a", "b", "c"}, restoredNames(t, f.store, afterC))
+
+	_, err := replica.Restore(t.Context(), f.store, filepath.Join(t.TempDir(), "x"), beforeReplication)
+	require.ErrorIs(t, err, replica.ErrNoReplica)
+}
+
+func TestSync_CheckpointKeepsGeneration(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t)
+	f.sync(t)
+	// Enough to push the WAL past the checkpoint threshold.
+	f.insert(t, "large", strings.Repeat("x", 5<<20))
+	f.sync(t)
+	info, err := os.Stat(f.path + "-wal")
+	require.NoError(t, err)
+	assert.Zero(t, info.Size(), "the WAL was checkpointed and truncated")
+	f.insert(t, "after checkpoint", "")
+	now := f.sync(t)
+
+	generations, err := f.store.Generations(t.Context())
+	require.NoError(t, err)
+	assert.Len(t, generations, 1, "the replicator's own checkpoint does not break continuity")
+	assert.Equal(t, []string{"after checkpoint", "large"}, restoredNames(t, f.store, now))
+}
+
+func TestSync_ForeignCheckpointStartsGeneration(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t)
+	f.sync(t)
+	f.insert(t, "a", "")
+
+	// Another connection checkpoints and resets the WAL before it is shipped.
+	other, err := sql.Open("sqlite", "file:"+f.path)
+	require.NoError(t, err)
+	_, err = other.ExecContext(t.Context(), "PRAGMA wal_checkpoint(TRUNCATE)")
+	require.NoError(t, err)
+	require.NoError(t, other.Close())
+	f.insert(t, "b", "")
+	now := f.sync(t)
+
+	generations, err := f.store.Generations(t.Context())
+	require.NoError(t, err)
+	assert.Len(t, generations, 2)
+	assert.Equal(t, []string{"a", "b"}, restoredNames(t, f.store, now))
+}
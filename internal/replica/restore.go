@@ -0,0 +1,131 @@
+package replica
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+var (
+	// ErrNoReplica is returned when the store has no generation started at or
+	// before the time to restore to.
+	ErrNoReplica = errors.New("no replica covers that time")
+
+	errMissingSegment = errors.New("replica segment missing")
+	errTruncated      = errors.New("replica segment truncated")
+)
+
+// Restore reconstructs the database as of at into a new file at dest: it
+// copies the snapshot of the newest generation started by then and applies
+// the segments shipped by then. The result can trail at by up to the sync
+// interval. It returns the time of the last segment applied, or of the
+// snapshot if there was none.
+func Restore(ctx context.Context, store Store, dest string, at time.Time) (time.Time, error) {
+	generations, err := store.Generations(ctx)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("listing generations: %w", err)
+	}
+	var generation time.Time
+	for _, g := range generations {
+		if !g.After(at) {
+			generation = g
+		}
+	}
+	if generation.IsZero() {
+		return time.Time{}, ErrNoReplica
+	}
+	segments, err := store.Segments(ctx, generation)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("listing segments: %w", err)
+	}
+
+	f, err := os.OpenFile(dest, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("creating %s: %w", dest, err)
+	}
+	restoredTo, err := rebuild(ctx, store, f, generation, segments, at)
+	err = errors.Join(err, f.Close())
+	if err != nil {
+		_ = os.Remove(dest)
+		return time.Time{}, err
+	}
+	return restoredTo, nil
+}
+
+func rebuild(ctx context.Context, store Store, f *os.File, generation time.Time, segments []Segment, at time.Time) (time.Time, error) {
+	snapshot, err := store.OpenSnapshot(ctx, generation)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("opening snapshot: %w", err)
+	}
+	_, err = io.Copy(f, snapshot)
+	if err = errors.Join(err, snapshot.Close()); err != nil {
+		return time.Time{}, fmt.Errorf("copying snapshot: %w", err)
+	}
+
+	header := make([]byte, dbHeaderSize)
+	if _, err = f.ReadAt(header, 0); err != nil {
+		return time.Time{}, fmt.Errorf("reading snapshot header: %w", err)
+	}
+	pageSize, err := databasePageSize(header)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	restoredTo := generation
+	for i, seg := range segments {
+		if seg.Shipped.After(at) {
+			break
+		}
+		if seg.Index != uint64(i)+1 {
+			return time.Time{}, fmt.Errorf("%w: %d", errMissingSegment, uint64(i)+1)
+		}
+		if err = applySegment(ctx, store, f, generation, seg, pageSize); err != nil {
+			return time.Time{}, err
+		}
+		restoredTo = seg.Shipped
+	}
+
+	// Mark the file as using a rollback journal: it has no WAL beside it.
+	if _, err = f.WriteAt([]byte{1, 1}, dbVersionOffset); err != nil {
+		return time.Time{}, fmt.Errorf("writing database header: %w", err)
+	}
+	if err = f.Sync(); err != nil {
+		return time.Time{}, fmt.Errorf("syncing database: %w", err)
+	}
+	return restoredTo, nil
+}
+
+// applySegment writes each frame's page into the database file, as a
+// checkpoint would, and resizes the file to the database size recorded in
+// each commit frame.
+func applySegment(ctx context.Context, store Store, f *os.File, generation time.Time, seg Segment, pageSize uint32) error {
+	r, err := store.OpenSegment(ctx, generation, seg)
+	if err != nil {
+		return fmt.Errorf("opening segment %d: %w", seg.Index, err)
+	}
+	data, err := io.ReadAll(r)
+	if err = errors.Join(err, r.Close()); err != nil {
+		return fmt.Errorf("reading segment %d: %w", seg.Index, err)
+	}
+
+	size := frameHeaderSize + int(pageSize)
+	if len(data)%size != 0 {
+		return fmt.Errorf("%w: segment %d", errTruncated, seg.Index)
+	}
+	for pos := 0; pos < len(data); pos += size {
+		fr := parseFrame(data[pos:])
+		page := data[pos+frameHeaderSize : pos+size]
+		if _, err = f.WriteAt(page, int64(fr.pgno-1)*int64(pageSize)); err != nil {
+			return fmt.Errorf("writing page %d: %w", fr.pgno, err)
+		}
+		if fr.commit != 0 {
+			if err = f.Truncate(int64(fr.commit) * int64(pageSize)); err != nil {
+				return fmt.Errorf("resizing database: %w", err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package replica
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	timeFormat   = "20060102T150405.000000000Z"
+	snapshotName = "snapshot.sqlite3"
+	segmentExt   = ".wal"
+)
+
+var errInvalidName = errors.New("invalid replica file name")
+
+// Segment is a run of committed WAL frames, numbered from 1 within its
+// generation. Every frame in it was committed before Shipped.
+type Segment struct {
+	Index   uint64
+	Shipped time.Time
+}
+
+// Store keeps replicas. A generation is a snapshot of the database file
+// followed by the segments written to the WAL after it; it is identified by
+// the time its snapshot was taken. Listings are oldest first.
+//
+// DirStore keeps them in a local directory; an S3-compatible store can
+// implement the same methods with one object per snapshot and segment.
+type Store interface {
+	PutSnapshot(ctx context.Context, generation time.Time, r io.Reader) error
+	PutSegment(ctx context.Context, generation time.Time, seg Segment, r io.Reader) error
+	Generations(ctx context.Context) ([]time.Time, error)
+	Segments(ctx context.Context, generation time.Time) ([]Segment, error)
+	OpenSnapshot(ctx context.Context, generation time.Time) (io.ReadCloser, error)
+	OpenSegment(ctx context.Context, generation time.Time, seg Segment) (io.ReadCloser, error)
+	DeleteGeneration(ctx context.Context, generation time.Time) error
+}
+
+// DirStore is a Store in a local directory, with a subdirectory per
+// generation.
+type DirStore struct {
+	dir string
+}
+
+var _ Store = (*DirStore)(nil)
+
+// NewDirStore returns a Store that keeps replicas under dir.
+func NewDirStore(dir string) *DirStore {
+	return &DirStore{dir: dir}
+}
+
+func (s *DirStore) PutSnapshot(_ context.Context, generation time.Time, r io.Reader) error {
+	return s.put(generation, snapshotName, r)
+}
+
+func (s *DirStore) PutSegment(_ context.Context, generation time.Time, seg Segment, r io.Reader) error {
+	return s.put(generation, segmentName(seg), r)
+}
+
+func (s *DirStore) Generations(context.Context) ([]time.Time, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("listing generations: %w", err)
+	}
+
+	var generations []time.Time
+	for _, entry := range entries {
+		generation, perr := time.Parse(timeFormat, entry.Name())
+		if perr != nil || !entry.IsDir() {
+			continue
+		}
+		// Only generations whose snapshot was written completely count.
+		if _, serr := os.Stat(filepath.Join(s.dir, entry.Name(), snapshotName)); serr == nil {
+			generations = append(generations, generation)
+		}
+	}
+	slices.SortFunc(generations, time.Time.Compare)
+	return generations, nil
+}
+
+func (s *DirStore) Segments(_ context.Context, generation time.Time) ([]Segment, error) {
+	entries, err := os.ReadDir(s.generationDir(generation))
+	if err != nil {
+		return nil, fmt.Errorf("listing segments: %w", err)
+	}
+
+	var segments []Segment
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), segmentExt) {
+			continue
+		}
+		seg, perr := parseSegmentName(entry.Name())
+		if perr != nil {
+			return nil, perr
+		}
+		segments = append(segments, seg)
+	}
+	slices.SortFunc(segments, func(a, b Segment) int { return cmp.Compare(a.Index, b.Index) })
+	return segments, nil
+}
+
+func (s *DirStore) OpenSnapshot(_ context.Context, generation time.Time) (io.ReadCloser, error) {
+	return s.open(generation, snapshotName)
+}
+
+func (s *DirStore) OpenSegment(_ context.Context, generation time.Time, seg Segment) (io.ReadCloser, error) {
+	return s.open(generation, segmentName(seg))
+}
+
+func (s *DirStore) DeleteGeneration(_ context.Context, generation time.Time) error {
+	if err := os.RemoveAll(s.generationDir(generation)); err != nil {
+		return fmt.Errorf("deleting generation: %w", err)
+	}
+	return nil
+}
+
+func (s *DirStore) generationDir(generation time.Time) string {
+	return filepath.Join(s.dir, generation.UTC().Format(timeFormat))
+}
+
+// put writes r to a temporary file and renames it into place, so readers
+// never see a partial file.
+func (s *DirStore) put(generation time.Time, name string, r io.Reader) (err error) {
+	dir := s.generationDir(generation)
+	if err = os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("creating generation directory: %w", err)
+	}
+	f, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", name, err)
+	}
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}()
+
+	if _, err = io.Copy(f, r); err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+	if err = f.Sync(); err != nil {
+		return fmt.Errorf("syncing %s: %w", name, err)
+	}
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("closing %s: %w", name, err)
+	}
+	if err = os.Rename(f.Name(), filepath.Join(dir, name)); err != nil {
+		return fmt.Errorf("renaming %s: %w", name, err)
+	}
+	return nil
+}
+
+func (s *DirStore) open(generation time.Time, name string) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(s.generationDir(generation), name))
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", name, err)
+	}
+	return f, nil
+}
+
+func segmentName(seg Segment) string {
+	return fmt.Sprintf("%016d-%s%s", seg.Index, seg.Shipped.UTC().Format(timeFormat), segmentExt)
+}
+
+func parseSegmentName(name string) (Segment, error) {
+	indexStr, rest, ok := strings.Cut(strings.TrimSuffix(name, segmentExt), "-")
+	if !ok {
+		return Segment{}, fmt.Errorf("%w: %s", errInvalidName, name)
+	}
+	index, err := strconv.ParseUint(indexStr, 10, 64)
+	if err != nil {
+		return Segment{}, fmt.Errorf("%w: %s: %w", errInvalidName, name, err)
+	}
+	shipped, err := time.Parse(timeFormat, rest)
+	if err != nil {
+		return Segment{}, fmt.Errorf("%w: %s: %w", errInvalidName, name, err)
+	}
+	return Segment{Index: index, Shipped: shipped}, nil
+}
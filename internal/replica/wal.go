@@ -0,0 +1,93 @@
+package replica
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// WAL and database file layouts, from https://www.sqlite.org/fileformat.html.
+const (
+	walHeaderSize   = 32
+	frameHeaderSize = 24
+
+	walMagicLE = 0x377f0682
+	walMagicBE = 0x377f0683
+
+	dbHeaderSize = 100
+	// dbPageSizeOffset holds the page size; 1 means 65536.
+	dbPageSizeOffset = 16
+	// dbVersionOffset holds the write and read versions: 2 for WAL mode, 1
+	// for the rollback journal.
+	dbVersionOffset = 18
+)
+
+var (
+	errInvalidWAL      = errors.New("invalid WAL header")
+	errInvalidDatabase = errors.New("invalid database header")
+)
+
+// walHeader is the part of the WAL header the replicator needs.
+type walHeader struct {
+	pageSize uint32
+	// salt changes every time the WAL restarts from the beginning; frames
+	// carry a copy, so stale frames from before a restart are told apart.
+	salt [8]byte
+}
+
+func parseWALHeader(b []byte) (walHeader, error) {
+	if len(b) < walHeaderSize {
+		return walHeader{}, fmt.Errorf("%w: %d bytes", errInvalidWAL, len(b))
+	}
+	if magic := binary.BigEndian.Uint32(b); magic != walMagicLE && magic != walMagicBE {
+		return walHeader{}, fmt.Errorf("%w: magic %#x", errInvalidWAL, magic)
+	}
+	h := walHeader{pageSize: binary.BigEndian.Uint32(b[8:])}
+	copy(h.salt[:], b[16:24])
+	return h, nil
+}
+
+// frame is a WAL frame header.
+type frame struct {
+	pgno uint32
+	// commit is the database size in pages after a commit frame, and 0 for
+	// every other frame.
+	commit uint32
+	salt   [8]byte
+}
+
+func parseFrame(b []byte) frame {
+	f := frame{pgno: binary.BigEndian.Uint32(b), commit: binary.BigEndian.Uint32(b[4:])}
+	copy(f.salt[:], b[8:16])
+	return f
+}
+
+// committedLength returns the length of the frames at the start of b that
+// belong to the WAL with salt and end with a commit frame. Frames after the
+// last commit belong to a transaction still in progress or rolled back.
+func committedLength(b []byte, pageSize uint32, salt [8]byte) int {
+	size := frameHeaderSize + int(pageSize)
+	end := 0
+	for pos := 0; pos+size <= len(b); pos += size {
+		f := parseFrame(b[pos:])
+		if f.salt != salt {
+			break
+		}
+		if f.commit != 0 {
+			end = pos + size
+		}
+	}
+	return end
+}
+
+// databasePageSize reads the page size from a database file header.
+func databasePageSize(header []byte) (uint32, error) {
+	if len(header) < dbHeaderSize || string(header[:16]) != "SQLite format 3\x00" {
+		return 0, errInvalidDatabase
+	}
+	size := uint32(binary.BigEndian.Uint16(header[dbPageSizeOffset:]))
+	if size == 1 {
+		size = 1 << 16
+	}
+	return size, nil
+}
@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"go-htmx-template/internal/components/authors"
+	"go-htmx-template/internal/components/core"
+	"go-htmx-template/internal/db/queries"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxAuthorName = 100
+	maxAuthorBio  = 1000
+)
+
+var (
+	errAuthorChanged   = errors.New("author has changed")
+	errVersionRequired = errors.New("send the version being edited, or its ETag in If-Match")
+)
+
+// Authors renders the authors page: the create form and a page of authors,
+// sorted and paged as the query asks. For htmx it returns just the list.
+func (h *Handler) Authors(w http.ResponseWriter, r *http.Request) {
+	list, err := parseAuthorList(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	listing, err := h.authorListing(r.Context(), list)
+	if err != nil {
+		h.serverError(w, "Failed to list authors", err)
+		return
+	}
+
+	w.Header().Add("Vary", "HX-Request")
+	if isFragmentRequest(r) {
+		h.html(r.Context(), w, http.StatusOK, authors.List(listing))
+		return
+	}
+	h.html(r.Context(), w, http.StatusOK, core.HTML("Authors", authors.Page(listing)))
+}
+
+// CreateAuthor adds an author. It answers with a fresh form and the new row,
+// or with the form and its errors and status 422.
+func (h *Handler) CreateAuthor(w http.ResponseWriter, r *http.Request) {
+	form, ok := parseAuthorForm(r)
+	if !ok {
+		h.html(r.Context(), w, http.StatusUnprocessableEntity, authors.CreateForm(form))
+		return
+	}
+
+	author, err := h.database.Queries().CreateAuthor(r.Context(), queries.CreateAuthorParams{
+		Name: form.Name,
+		Bio:  nullString(form.Bio),
+	})
+	if err != nil {
+		h.serverError(w, "Failed to create author", err)
+		return
+	}
+	h.html(r.Context(), w, http.StatusOK, authors.Created(author))
+}
+
+// AuthorRow returns an author's table row, as when an edit is cancelled. Its
+// ETag is the author's version, for If-Match on updates.
+func (h *Handler) AuthorRow(w http.ResponseWriter, r *http.Request) {
+	author, ok := h.author(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("ETag", authorETag(author))
+	h.html(r.Context(), w, http.StatusOK, authors.Row(author))
+}
+
+// EditAuthor returns an author's row as inputs to edit in place.
+func (h *Handler) EditAuthor(w http.ResponseWriter, r *http.Request) {
+	author, ok := h.author(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("ETag", authorETag(author))
+	h.html(r.Context(), w, http.StatusOK, authors.EditRow(authors.FormFor(author)))
+}
+
+// UpdateAuthor saves an edited author and returns its row, or the edit row
+// with its errors and status 422. The update applies only to the version the
+// edit started from, sent by the form: if the author has changed since, it
+// answers 409 with both versions to choose from. Clients without htmx send
+// the ETag instead, in If-Match, and get 412 if it is stale.
+func (h *Handler) UpdateAuthor(w http.ResponseWriter, r *http.Request) {
+	author, ok := h.author(w, r)
+	if !ok {
+		return
+	}
+	form, ok := parseAuthorForm(r)
+	form.ID = author.ID
+
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch != "" {
+		if !etagMatches(ifMatch, authorETag(author)) {
+			w.Header().Set("ETag", authorETag(author))
+			http.Error(w, errAuthorChanged.Error(), http.StatusPreconditionFailed)
+			return
+		}
+		form.Version = author.Version
+	} else {
+		var err error
+		if form.Version, err = strconv.ParseInt(r.PostFormValue("version"), 10, 64); err != nil {
+			http.Error(w, errVersionRequired.Error(), http.StatusPreconditionRequired)
+			return
+		}
+	}
+	if !ok {
+		h.html(r.Context(), w, http.StatusUnprocessableEntity, authors.EditRow(form))
+		return
+	}
+
+	updated, err := h.database.Queries().UpdateAuthor(r.Context(), queries.UpdateAuthorParams{
+		Name:    form.Name,
+		Bio:     nullString(form.Bio),
+		ID:      author.ID,
+		Version: form.Version,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		h.updateConflict(w, r, form, ifMatch != "")
+		return
+	}
+	if err != nil {
+		h.serverError(w, "Failed to update author", err)
+		return
+	}
+	w.Header().Set("ETag", authorETag(updated))
+	h.html(r.Context(), w, http.StatusOK, authors.Row(updated))
+}
+
+// updateConflict answers an update made from a version that is no longer
+// current, or 404 if the author has been deleted since.
+func (h *Handler) updateConflict(w http.ResponseWriter, r *http.Request, form authors.Form, ifMatch bool) {
+	current, ok := h.author(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("ETag", authorETag(current))
+	if ifMatch {
+		http.Error(w, errAuthorChanged.Error(), http.StatusPreconditionFailed)
+		return
+	}
+	h.html(r.Context(), w, http.StatusConflict, authors.ConflictRow(form, current))
+}
+
+// DeleteAuthor moves an author to the trash. The response swaps its row away
+// and offers to undo in a toast.
+func (h *Handler) DeleteAuthor(w http.ResponseWriter, r *http.Request) {
+	id, ok := authorID(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	author, err := h.database.Queries().TrashAuthor(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		h.serverError(w, "Failed to delete author", err)
+		return
+	}
+	h.html(r.Context(), w, http.StatusOK, authors.Deleted(author))
+}
+
+// author loads the author named by the {id} path value, answering 404 itself
+// when there is none.
+func (h *Handler) author(w http.ResponseWriter, r *http.Request) (queries.Author, bool) {
+	id, ok := authorID(r)
+	if !ok {
+		http.NotFound(w, r)
+		return queries.Author{}, false
+	}
+	author, err := h.database.Queries().GetAuthor(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return queries.Author{}, false
+	}
+	if err != nil {
+		h.serverError(w, "Failed to get author", err)
+		return queries.Author{}, false
+	}
+	return author, true
+}
+
+// authorETag identifies an author's version.
+func authorETag(author queries.Author) string {
+	return `"` + strconv.FormatInt(author.Version, 10) + `"`
+}
+
+// etagMatches reports whether an If-Match header is * or lists etag. Weak
+// ETags never match.
+func etagMatches(header, etag string) bool {
+	for tag := range strings.SplitSeq(header, ",") {
+		if tag = strings.TrimSpace(tag); tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func authorID(r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	return id, err == nil && id > 0
+}
+
+// parseAuthorForm reads and trims the submitted fields, reporting whether
+// they are valid.
+func parseAuthorForm(r *http.Request) (authors.Form, bool) {
+	form := authors.Form{
+		Name:   strings.TrimSpace(r.PostFormValue("name")),
+		Bio:    strings.TrimSpace(r.PostFormValue("bio")),
+		Errors: make(map[string]string),
+	}
+	switch {
+	case form.Name == "":
+		form.Errors["name"] = "Name is required."
+	case utf8.RuneCountInString(form.Name) > maxAuthorName:
+		form.Errors["name"] = "Name must be at most " + strconv.Itoa(maxAuthorName) + " characters."
+	}
+	if utf8.RuneCountInString(form.Bio) > maxAuthorBio {
+		form.Errors["bio"] = "Bio must be at most " + strconv.Itoa(maxAuthorBio) + " characters."
+	}
+	return form, len(form.Errors) == 0
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
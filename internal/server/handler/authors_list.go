@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"go-htmx-template/internal/components/authors"
+	"go-htmx-template/internal/components/core"
+	"go-htmx-template/internal/db/queries"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+)
+
+const (
+	sortByName    = "name"
+	sortByCreated = "created"
+
+	// createdAtLayout is how CURRENT_TIMESTAMP stores created_at. Cursors
+	// use it so they compare with the column as text.
+	createdAtLayout = "2006-01-02 15:04:05"
+)
+
+var errInvalidSort = errors.New("sort must be name or created and dir asc or desc")
+
+// authorList is a page of the authors list as requested in the URL.
+type authorList struct {
+	sort string
+	desc bool
+	page pageRequest
+}
+
+// parseAuthorList reads the sort and dir query parameters, which default to
+// name ascending, and the page.
+func parseAuthorList(r *http.Request) (authorList, error) {
+	page, err := parsePageRequest(r)
+	if err != nil {
+		return authorList{}, err
+	}
+	query := r.URL.Query()
+	list := authorList{sort: cmp.Or(query.Get("sort"), sortByName), page: page}
+	if list.sort != sortByName && list.sort != sortByCreated {
+		return authorList{}, errInvalidSort
+	}
+	switch query.Get("dir") {
+	case "", "asc":
+	case "desc":
+		list.desc = true
+	default:
+		return authorList{}, errInvalidSort
+	}
+	return list, nil
+}
+
+// authorListing loads the requested page and links its neighbours.
+func (h *Handler) authorListing(ctx context.Context, list authorList) (authors.Listing, error) {
+	limit := list.page.limit
+	var rows []queries.Author
+	var hasPrev, hasNext bool
+	if before := list.page.before; before != nil {
+		// Walk back in the opposite order, then put the rows in this one.
+		var err error
+		rows, err = h.listAuthors(ctx, list.sort, !list.desc, before, limit+1)
+		if err != nil {
+			return authors.Listing{}, err
+		}
+		if len(rows) <= limit {
+			// Nothing precedes these rows: show a full first page instead.
+			return h.authorListing(ctx, authorList{sort: list.sort, desc: list.desc, page: pageRequest{limit: limit}})
+		}
+		rows = rows[:limit]
+		slices.Reverse(rows)
+		hasPrev, hasNext = true, true
+	} else {
+		var err error
+		rows, err = h.listAuthors(ctx, list.sort, list.desc, list.page.after, limit+1)
+		if err != nil {
+			return authors.Listing{}, err
+		}
+		hasPrev, hasNext = list.page.after != nil, len(rows) > limit
+		rows = rows[:min(len(rows), limit)]
+	}
+
+	listing := authors.Listing{
+		URL:     list.url(list.sort, list.desc, "", ""),
+		Authors: rows,
+		Name:    list.column("Name", sortByName),
+		Created: list.column("Added", sortByCreated),
+		Pager:   core.Pager{Target: "#authors-list"},
+	}
+	switch {
+	case list.page.after != nil:
+		listing.URL = list.url(list.sort, list.desc, "after", list.page.after.String())
+	case list.page.before != nil:
+		listing.URL = list.url(list.sort, list.desc, "before", list.page.before.String())
+	}
+	switch {
+	case hasPrev && len(rows) == 0:
+		listing.Pager.Prev = list.url(list.sort, list.desc, "", "")
+	case hasPrev:
+		listing.Pager.Prev = list.url(list.sort, list.desc, "before", list.cursorOf(rows[0]).String())
+	}
+	if hasNext {
+		listing.Pager.Next = list.url(list.sort, list.desc, "after", list.cursorOf(rows[len(rows)-1]).String())
+	}
+	return listing, nil
+}
+
+// listAuthors returns up to limit authors in the given order, after the
+// cursor if there is one.
+func (h *Handler) listAuthors(ctx context.Context, sort string, desc bool, after *cursor, limit int) ([]queries.Author, error) {
+	q, n := h.database.Queries(), int64(limit)
+	switch {
+	case sort == sortByCreated && after == nil && desc:
+		return q.ListAuthorsByCreatedDesc(ctx, n)
+	case sort == sortByCreated && after == nil:
+		return q.ListAuthorsByCreated(ctx, n)
+	case sort == sortByCreated && desc:
+		return q.ListAuthorsByCreatedDescAfter(ctx, queries.ListAuthorsByCreatedDescAfterParams{
+			CreatedAt: after.value, ID: after.id, Limit: n,
+		})
+	case sort == sortByCreated:
+		return q.ListAuthorsByCreatedAfter(ctx, queries.ListAuthorsByCreatedAfterParams{
+			CreatedAt: after.value, ID: after.id, Limit: n,
+		})
+	case after == nil && desc:
+		return q.ListAuthorsByNameDesc(ctx, n)
+	case after == nil:
+		return q.ListAuthorsByName(ctx, n)
+	case desc:
+		return q.ListAuthorsByNameDescAfter(ctx, queries.ListAuthorsByNameDescAfterParams{
+			Name: after.value, ID: after.id, Limit: n,
+		})
+	default:
+		return q.ListAuthorsByNameAfter(ctx, queries.ListAuthorsByNameAfterParams{
+			Name: after.value, ID: after.id, Limit: n,
+		})
+	}
+}
+
+func (l authorList) cursorOf(author queries.Author) cursor {
+	if l.sort == sortByCreated {
+		return cursor{value: author.CreatedAt.UTC().Format(createdAtLayout), id: author.ID}
+	}
+	return cursor{value: author.Name, id: author.ID}
+}
+
+// column is the header for sorting by sort. Choosing the current ascending
+// sort again reverses it.
+func (l authorList) column(label, sort string) authors.Column {
+	sorted := l.sort == sort
+	return authors.Column{
+		Label:  label,
+		URL:    l.url(sort, sorted && !l.desc, "", ""),
+		Sorted: sorted,
+		Desc:   sorted && l.desc,
+	}
+}
+
+// url links to the list in the given order, from the cursor named by
+// cursorParam if it is set, keeping the page size. Defaults are left out.
+func (l authorList) url(sort string, desc bool, cursorParam, cursor string) string {
+	query := url.Values{}
+	if sort != sortByName {
+		query.Set("sort", sort)
+	}
+	if desc {
+		query.Set("dir", "desc")
+	}
+	if l.page.limit != defaultPageSize {
+		query.Set("limit", strconv.Itoa(l.page.limit))
+	}
+	if cursorParam != "" {
+		query.Set(cursorParam, cursor)
+	}
+	if len(query) == 0 {
+		return "/authors"
+	}
+	return "/authors?" + query.Encode()
+}
@@ -0,0 +1,255 @@
+package handler_test
+
+import (
+	"context"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go-htmx-template/internal/server/handler"
+)
+
+var (
+	authorNamePattern = regexp.MustCompile(`<td class="py-2 font-medium">([^<]*)</td>`)
+	authorRowPattern  = regexp.MustCompile(`<tr id="author-(\d+)"`)
+	pageLinkPattern   = regexp.MustCompile(`<a href="([^"]*)"[^>]*>\s*(Previous|Next)\s*</a>`)
+)
+
+// listPage is a rendered page of the authors list.
+type listPage struct {
+	names      []string
+	ids        []string
+	prev, next string
+}
+
+func getAuthors(t *testing.T, h *handler.Handler, target string, fragment bool) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, target, nil)
+	if fragment {
+		req.Header.Set("HX-Request", "true")
+	}
+	rec := httptest.NewRecorder()
+	h.Authors(rec, req)
+	return rec
+}
+
+func listAuthors(t *testing.T, h *handler.Handler, target string) listPage {
+	t.Helper()
+	rec := getAuthors(t, h, target, true)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var page listPage
+	for _, m := range authorNamePattern.FindAllStringSubmatch(rec.Body.String(), -1) {
+		page.names = append(page.names, m[1])
+	}
+	for _, m := range authorRowPattern.FindAllStringSubmatch(rec.Body.String(), -1) {
+		page.ids = append(page.ids, m[1])
+	}
+	for _, m := range pageLinkPattern.FindAllStringSubmatch(rec.Body.String(), -1) {
+		if m[2] == "Previous" {
+			page.prev = html.UnescapeString(m[1])
+		} else {
+			page.next = html.UnescapeString(m[1])
+		}
+	}
+	return page
+}
+
+func TestAuthors_Pagination(t *testing.T) {
+	t.Parallel()
+
+	h := newDBHandler(t)
+	for _, name := range []string{"Eve", "Bob", "Dan", "Ada", "Cy"} {
+		createAuthor(t, h, name)
+	}
+
+	tests := []struct {
+		name  string
+		first string
+		pages [][]string
+	}{
+		{
+			name:  "name ascending",
+			first: "/authors?limit=2",
+			pages: [][]string{{"Ada", "Bob"}, {"Cy", "Dan"}, {"Eve"}},
+		},
+		{
+			name:  "name descending",
+			first: "/authors?dir=desc&limit=2",
+			pages: [][]string{{"Eve", "Dan"}, {"Cy", "Bob"}, {"Ada"}},
+		},
+		{
+			// Authors created in the same second are ordered by id.
+			name:  "created ascending",
+			first: "/authors?sort=created&limit=2",
+			pages: [][]string{{"Eve", "Bob"}, {"Dan", "Ada"}, {"Cy"}},
+		},
+		{
+			name:  "created descending",
+			first: "/authors?sort=created&dir=desc&limit=2",
+			pages: [][]string{{"Cy", "Ada"}, {"Dan", "Bob"}, {"Eve"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Forward through every page.
+			var targets []string
+			target := tt.first
+			for i, want := range tt.pages {
+				page := listAuthors(t, h, target)
+				assert.Equal(t, want, page.names, "page %d", i+1)
+				assert.Equal(t, i > 0, page.prev != "", "page %d has a previous link", i+1)
+				assert.Equal(t, i < len(tt.pages)-1, page.next != "", "page %d has a next link", i+1)
+				targets = append(targets, target)
+				target = page.next
+			}
+
+			// And back again from the last.
+			page := listAuthors(t, h, targets[len(targets)-1])
+			for i := len(tt.pages) - 2; i >= 0; i-- {
+				page = listAuthors(t, h, page.prev)
+				assert.Equal(t, tt.pages[i], page.names, "back to page %d", i+1)
+			}
+			assert.Empty(t, page.prev)
+		})
+	}
+}
+
+func TestAuthors_PaginationBreaksTiesByID(t *testing.T) {
+	t.Parallel()
+
+	// Authors 1, 3, 5 and 7 share a name, as do 2, 4 and 6, and all are
+	// created in the same second, so every page boundary falls on a tie.
+	h := newDBHandler(t)
+	for _, name := range []string{"Ada", "Bob", "Ada", "Bob", "Ada", "Bob", "Ada"} {
+		createAuthor(t, h, name)
+	}
+
+	tests := []struct {
+		name  string
+		first string
+		ids   []string
+	}{
+		{name: "name ascending", first: "/authors?limit=2", ids: []string{"1", "3", "5", "7", "2", "4", "6"}},
+		{name: "name descending", first: "/authors?dir=desc&limit=2", ids: []string{"6", "4", "2", "7", "5", "3", "1"}},
+		{name: "created ascending", first: "/authors?sort=created&limit=2", ids: []string{"1", "2", "3", "4", "5", "6", "7"}},
+		{name: "created descending", first: "/authors?sort=created&dir=desc&limit=2", ids: []string{"7", "6", "5", "4", "3", "2", "1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var ids []string
+			var pages []listPage
+			for target := tt.first; target != ""; {
+				page := listAuthors(t, h, target)
+				require.NotEmpty(t, page.ids)
+				require.Less(t, len(pages), len(tt.ids), "pagination does not end")
+				ids = append(ids, page.ids...)
+				pages = append(pages, page)
+				target = page.next
+			}
+			assert.Equal(t, tt.ids, ids)
+			assert.Len(t, pages, 4)
+
+			var back []string
+			for page := pages[len(pages)-1]; page.prev != ""; {
+				page = listAuthors(t, h, page.prev)
+				back = append(page.ids, back...)
+			}
+			assert.Equal(t, tt.ids[:len(tt.ids)-1], back)
+		})
+	}
+}
+
+func TestAuthors_PaginationIsStable(t *testing.T) {
+	t.Parallel()
+
+	h := newDBHandler(t)
+	for _, name := range []string{"Bob", "Dan", "Fay", "Hal"} {
+		createAuthor(t, h, name)
+	}
+
+	page := listAuthors(t, h, "/authors?limit=2")
+	require.Equal(t, []string{"Bob", "Dan"}, page.names)
+
+	// Rows added before the cursor do not shift the next page.
+	createAuthor(t, h, "Ada")
+	createAuthor(t, h, "Cy")
+	page = listAuthors(t, h, page.next)
+	assert.Equal(t, []string{"Fay", "Hal"}, page.names)
+	assert.Empty(t, page.next)
+
+	// Going back from a page whose predecessors grew shows the rows just
+	// before it.
+	page = listAuthors(t, h, page.prev)
+	assert.Equal(t, []string{"Cy", "Dan"}, page.names)
+	page = listAuthors(t, h, page.prev)
+	assert.Equal(t, []string{"Ada", "Bob"}, page.names)
+	assert.Empty(t, page.prev)
+}
+
+func TestAuthors_SortHeaders(t *testing.T) {
+	t.Parallel()
+
+	h := newDBHandler(t)
+
+	body := getAuthors(t, h, "/authors", true).Body.String()
+	assert.Contains(t, body, `<th class="py-2" aria-sort="ascending"><a href="/authors?dir=desc"`)
+	assert.Contains(t, body, `<th class="py-2" aria-sort="none"><a href="/authors?sort=created"`)
+
+	body = getAuthors(t, h, "/authors?dir=desc&limit=5", true).Body.String()
+	assert.Contains(t, body, `<th class="py-2" aria-sort="descending"><a href="/authors?limit=5"`)
+}
+
+func TestAuthors_InvalidListQuery(t *testing.T) {
+	t.Parallel()
+
+	h := newDBHandler(t)
+	for _, target := range []string{
+		"/authors?sort=bio",
+		"/authors?dir=up",
+		"/authors?limit=0",
+		"/authors?limit=101",
+		"/authors?after=nope",
+		"/authors?after=QQ.x",
+		"/authors?after=QQ.1&before=QQ.2",
+	} {
+		rec := getAuthors(t, h, target, false)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, target)
+	}
+}
+
+func TestAuthors_Fragment(t *testing.T) {
+	t.Parallel()
+
+	h := newDBHandler(t)
+
+	rec := getAuthors(t, h, "/authors", true)
+	assert.Equal(t, "HX-Request", rec.Header().Get("Vary"))
+	assert.Contains(t, rec.Body.String(), `<div id="authors-list"`)
+	assert.NotContains(t, rec.Body.String(), "<html")
+	assert.NotContains(t, rec.Body.String(), `id="author-form"`)
+
+	rec = getAuthors(t, h, "/authors", false)
+	assert.Equal(t, "HX-Request", rec.Header().Get("Vary"))
+	assert.Contains(t, rec.Body.String(), "<html")
+	assert.Contains(t, rec.Body.String(), `id="author-form"`)
+
+	// A history restore rebuilds the whole page.
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/authors", nil)
+	req.Header.Set("HX-Request", "true")
+	req.Header.Set("HX-History-Restore-Request", "true")
+	rec = httptest.NewRecorder()
+	h.Authors(rec, req)
+	assert.Contains(t, rec.Body.String(), "<html")
+}
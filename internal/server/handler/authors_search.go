@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"errors"
+	"go-htmx-template/internal/components/authors"
+	"go-htmx-template/internal/components/core"
+	"go-htmx-template/internal/db/queries"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxSearchQuery = 100
+	searchLimit    = 20
+)
+
+var errSearchTooLong = errors.New("search must be at most " + strconv.Itoa(maxSearchQuery) + " characters")
+
+// SearchAuthors finds the authors whose name or bio match the q parameter,
+// best first. For htmx it returns just the results.
+func (h *Handler) SearchAuthors(w http.ResponseWriter, r *http.Request) {
+	search := authors.Search{Query: strings.TrimSpace(r.URL.Query().Get("q"))}
+	if utf8.RuneCountInString(search.Query) > maxSearchQuery {
+		http.Error(w, errSearchTooLong.Error(), http.StatusBadRequest)
+		return
+	}
+	if match := ftsQuery(search.Query); match != "" {
+		var err error
+		search.Results, err = h.database.Queries().SearchAuthors(r.Context(), queries.SearchAuthorsParams{
+			Query: match,
+			Limit: searchLimit,
+		})
+		if err != nil {
+			h.serverError(w, "Failed to search authors", err)
+			return
+		}
+	}
+
+	w.Header().Add("Vary", "HX-Request")
+	if isFragmentRequest(r) {
+		h.html(r.Context(), w, http.StatusOK, authors.SearchResults(search))
+		return
+	}
+	h.html(r.Context(), w, http.StatusOK, core.HTML("Search authors", authors.SearchPage(search)))
+}
+
+// ftsQuery turns a search as typed into an FTS5 query matching every word as
+// a prefix, so results narrow while a word is still being typed. Each word is
+// quoted, which makes FTS5 operators and punctuation in it plain text.
+func ftsQuery(s string) string {
+	words := strings.Fields(s)
+	for i, word := range words {
+		words[i] = `"` + strings.ReplaceAll(word, `"`, `""`) + `"*`
+	}
+	return strings.Join(words, " ")
+}
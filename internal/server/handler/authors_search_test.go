@@ -0,0 +1,144 @@
+package handler_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go-htmx-template/internal/server/handler"
+)
+
+func searchAuthors(t *testing.T, h *handler.Handler, q string, fragment bool) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet,
+		"/authors/search?"+url.Values{"q": {q}}.Encode(), nil)
+	if fragment {
+		req.Header.Set("HX-Request", "true")
+	}
+	rec := httptest.NewRecorder()
+	h.SearchAuthors(rec, req)
+	return rec
+}
+
+func newSearchHandler(t *testing.T) *handler.Handler {
+	t.Helper()
+	h := newDBHandler(t)
+	for _, author := range []url.Values{
+		{"name": {"Ada Lovelace"}, "bio": {"Wrote the first program for the Analytical Engine."}},
+		{"name": {"Charles Babbage"}, "bio": {"Designed the Analytical Engine. Admired Lovelace's notes."}},
+		{"name": {"Zoë <Smith>"}, "bio": {"Writes about engines & <script>."}},
+	} {
+		rec := serveAuthor(h.CreateAuthor, http.MethodPost, "/authors", 0, author)
+		require.Equal(t, http.StatusOK, rec.Code)
+	}
+	return h
+}
+
+func TestSearchAuthors(t *testing.T) {
+	t.Parallel()
+
+	h := newSearchHandler(t)
+
+	tests := []struct {
+		name    string
+		q       string
+		want    []string
+		missing []string
+	}{
+		{
+			name: "name matches rank above bio matches",
+			q:    "lovelace",
+			want: []string{`<mark class="bg-yellow-300 text-black">Lovelace</mark></p>`, "Admired <mark"},
+		},
+		{
+			name: "prefix of the word being typed",
+			q:    "analyt",
+			want: []string{"the <mark class=\"bg-yellow-300 text-black\">Analytical</mark> Engine"},
+		},
+		{
+			name:    "every word must match",
+			q:       "analytical designed",
+			want:    []string{"<mark class=\"bg-yellow-300 text-black\">Designed</mark>"},
+			missing: []string{"Ada"},
+		},
+		{
+			name: "diacritics are ignored",
+			q:    "zoe",
+			want: []string{`<mark class="bg-yellow-300 text-black">Zoë</mark> &lt;Smith&gt;`, "&amp; &lt;script&gt;"},
+		},
+		{
+			name: "operators are plain text",
+			q:    `ada OR "babbage`,
+			want: []string{"No authors match “ada OR &#34;babbage”."},
+		},
+		{
+			name: "punctuation only",
+			q:    "(*)",
+			want: []string{"No authors match"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := searchAuthors(t, h, tt.q, true)
+			require.Equal(t, http.StatusOK, rec.Code)
+			body := rec.Body.String()
+			for _, want := range tt.want {
+				assert.Contains(t, body, want)
+			}
+			for _, missing := range tt.missing {
+				assert.NotContains(t, body, missing)
+			}
+		})
+	}
+
+	t.Run("order", func(t *testing.T) {
+		t.Parallel()
+
+		body := searchAuthors(t, h, "lovelace", true).Body.String()
+		assert.Less(t, strings.Index(body, "Ada"), strings.Index(body, "Charles"))
+	})
+}
+
+func TestSearchAuthors_FollowsChanges(t *testing.T) {
+	t.Parallel()
+
+	h := newSearchHandler(t)
+
+	rec := serveAuthor(h.UpdateAuthor, http.MethodPut, "/authors/1", 1, url.Values{"name": {"Augusta King"}, "version": {"1"}})
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, searchAuthors(t, h, "augusta", true).Body.String(), "Augusta</mark> King")
+	assert.NotContains(t, searchAuthors(t, h, "program", true).Body.String(), "<li")
+
+	rec = serveAuthor(h.DeleteAuthor, http.MethodDelete, "/authors/2", 2, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, searchAuthors(t, h, "babbage", true).Body.String(), "No authors match")
+}
+
+func TestSearchAuthors_Requests(t *testing.T) {
+	t.Parallel()
+
+	h := newSearchHandler(t)
+
+	rec := searchAuthors(t, h, "", true)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `<div id="author-search-results" aria-live="polite"></div>`, rec.Body.String())
+
+	rec = searchAuthors(t, h, "ada", false)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "HX-Request", rec.Header().Get("Vary"))
+	assert.Contains(t, rec.Body.String(), "<html")
+	assert.Contains(t, rec.Body.String(), `value="ada"`)
+	assert.Contains(t, rec.Body.String(), "Lovelace")
+
+	rec = searchAuthors(t, h, strings.Repeat("a", 101), true)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
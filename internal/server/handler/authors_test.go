@@ -0,0 +1,199 @@
+package handler_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go-htmx-template/internal/server/handler"
+)
+
+// serveAuthor calls fn with a request for method and path, setting the form
+// as the body and id as the {id} path value.
+func serveAuthor(fn http.HandlerFunc, method, path string, id int64, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequestWithContext(context.Background(), method, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if id != 0 {
+		req.SetPathValue("id", strconv.FormatInt(id, 10))
+	}
+	rec := httptest.NewRecorder()
+	fn(rec, req)
+	return rec
+}
+
+func createAuthor(t *testing.T, h *handler.Handler, name string) {
+	t.Helper()
+	rec := serveAuthor(h.CreateAuthor, http.MethodPost, "/authors", 0, url.Values{"name": {name}, "bio": {"bio of " + name}})
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestAuthors_CreateAndList(t *testing.T) {
+	t.Parallel()
+
+	h := newDBHandler(t)
+
+	rec := serveAuthor(h.Authors, http.MethodGet, "/authors", 0, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `id="authors-empty"`)
+
+	rec = serveAuthor(h.CreateAuthor, http.MethodPost, "/authors", 0, url.Values{"name": {"  Ada  "}, "bio": {"Analyst"}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, `<form id="author-form"`)
+	assert.Contains(t, body, `hx-swap-oob="afterbegin:#authors-body"`)
+	assert.Contains(t, body, `<tr id="author-1"`)
+	assert.Contains(t, body, "<td class=\"py-2 font-medium\">Ada</td>", "the name is trimmed")
+
+	rec = serveAuthor(h.Authors, http.MethodGet, "/authors", 0, nil)
+	assert.Contains(t, rec.Body.String(), "Analyst")
+	assert.NotContains(t, rec.Body.String(), `id="authors-empty"`)
+}
+
+func TestAuthors_CreateInvalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		form url.Values
+		want string
+	}{
+		{name: "missing name", form: url.Values{"name": {"   "}, "bio": {"kept"}}, want: "Name is required."},
+		{name: "long name", form: url.Values{"name": {strings.Repeat("a", 101)}}, want: "Name must be at most 100 characters."},
+		{name: "long bio", form: url.Values{"name": {"a"}, "bio": {strings.Repeat("b", 1001)}}, want: "Bio must be at most 1000 characters."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := newDBHandler(t)
+			rec := serveAuthor(h.CreateAuthor, http.MethodPost, "/authors", 0, tt.form)
+			assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.want)
+			assert.Contains(t, rec.Body.String(), `<form id="author-form"`)
+			assert.NotContains(t, rec.Body.String(), "hx-swap-oob")
+		})
+	}
+}
+
+func TestAuthors_EditInPlace(t *testing.T) {
+	t.Parallel()
+
+	h := newDBHandler(t)
+	createAuthor(t, h, "Ada")
+
+	rec := serveAuthor(h.EditAuthor, http.MethodGet, "/authors/1/edit", 1, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `value="Ada"`)
+	assert.Contains(t, rec.Body.String(), `hx-put="/authors/1"`)
+	assert.Contains(t, rec.Body.String(), `<input type="hidden" name="version" value="1">`)
+
+	rec = serveAuthor(h.UpdateAuthor, http.MethodPut, "/authors/1", 1, url.Values{"name": {""}, "version": {"1"}})
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Name is required.")
+	assert.Contains(t, rec.Body.String(), `hx-put="/authors/1"`, "the edit row is returned")
+
+	rec = serveAuthor(h.UpdateAuthor, http.MethodPut, "/authors/1", 1, url.Values{"name": {"Grace"}, "bio": {"Admiral"}, "version": {"1"}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Grace")
+	assert.Contains(t, rec.Body.String(), `hx-get="/authors/1/edit"`, "the display row is returned")
+
+	rec = serveAuthor(h.AuthorRow, http.MethodGet, "/authors/1", 1, nil)
+	assert.Contains(t, rec.Body.String(), "Admiral")
+
+	rec = serveAuthor(h.UpdateAuthor, http.MethodPut, "/authors/2", 2, url.Values{"name": {"Nobody"}, "version": {"1"}})
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestAuthors_Delete(t *testing.T) {
+	t.Parallel()
+
+	h := newDBHandler(t)
+	createAuthor(t, h, "Ada")
+
+	rec := serveAuthor(h.DeleteAuthor, http.MethodDelete, "/authors/1", 1, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, strings.HasPrefix(rec.Body.String(), `<div id="authors-toast" hx-swap-oob="true"`),
+		"only the toast is swapped in, so the row is swapped away")
+	assert.Contains(t, rec.Body.String(), `hx-post="/authors/1/restore"`)
+
+	rec = serveAuthor(h.AuthorRow, http.MethodGet, "/authors/1", 1, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = serveAuthor(h.DeleteAuthor, http.MethodDelete, "/authors/1", 1, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code, "already in the trash")
+}
+
+func TestAuthors_UpdateConflict(t *testing.T) {
+	t.Parallel()
+
+	h := newDBHandler(t)
+	createAuthor(t, h, "Ada")
+
+	// Two edits start from version 1; the first to save wins.
+	rec := serveAuthor(h.UpdateAuthor, http.MethodPut, "/authors/1", 1, url.Values{"name": {"Ada King"}, "version": {"1"}})
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+
+	rec = serveAuthor(h.UpdateAuthor, http.MethodPut, "/authors/1", 1,
+		url.Values{"name": {"Ada Lovelace"}, "bio": {"Mathematician"}, "version": {"1"}})
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, `<tr id="author-1"`)
+	assert.Contains(t, body, "<td class=\"pr-4\">Ada Lovelace</td><td>Ada King</td>", "both names are shown")
+	assert.Contains(t, body, `<input type="hidden" name="version" value="2">`, "keeping the edit saves over the current version")
+	assert.Contains(t, body, `<input type="hidden" name="name" value="Ada Lovelace">`)
+	assert.Contains(t, serveAuthor(h.AuthorRow, http.MethodGet, "/authors/1", 1, nil).Body.String(), "Ada King",
+		"the conflicting edit is not saved")
+
+	rec = serveAuthor(h.UpdateAuthor, http.MethodPut, "/authors/1", 1,
+		url.Values{"name": {"Ada Lovelace"}, "bio": {"Mathematician"}, "version": {"2"}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Mathematician")
+
+	rec = serveAuthor(h.UpdateAuthor, http.MethodPut, "/authors/1", 1, url.Values{"name": {"Ada"}})
+	assert.Equal(t, http.StatusPreconditionRequired, rec.Code, "updates must name the version they edit")
+}
+
+func TestAuthors_UpdateIfMatch(t *testing.T) {
+	t.Parallel()
+
+	h := newDBHandler(t)
+	createAuthor(t, h, "Ada")
+
+	rec := serveAuthor(h.AuthorRow, http.MethodGet, "/authors/1", 1, nil)
+	etag := rec.Header().Get("ETag")
+	require.Equal(t, `"1"`, etag)
+
+	put := func(ifMatch, name string) *httptest.ResponseRecorder {
+		form := url.Values{"name": {name}}
+		req := httptest.NewRequestWithContext(context.Background(), http.MethodPut, "/authors/1", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("If-Match", ifMatch)
+		req.SetPathValue("id", "1")
+		rec := httptest.NewRecorder()
+		h.UpdateAuthor(rec, req)
+		return rec
+	}
+
+	rec = put(etag, "Ada King")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+
+	rec = put(etag, "Ada Lovelace")
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"), "the current ETag is returned")
+
+	for _, ifMatch := range []string{`"7", "2"`, "*"} {
+		rec = put(ifMatch, "Ada "+ifMatch)
+		assert.Equal(t, http.StatusOK, rec.Code, ifMatch)
+	}
+	assert.Equal(t, http.StatusPreconditionFailed, put(`W/"4"`, "Ada").Code, "weak ETags never match")
+}
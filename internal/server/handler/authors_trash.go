@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"go-htmx-template/internal/components/authors"
+	"go-htmx-template/internal/components/core"
+	"net/http"
+)
+
+// Trash lists the deleted authors, most recently deleted first.
+func (h *Handler) Trash(w http.ResponseWriter, r *http.Request) {
+	trashed, err := h.database.Queries().ListTrashedAuthors(r.Context())
+	if err != nil {
+		h.serverError(w, "Failed to list deleted authors", err)
+		return
+	}
+	h.html(r.Context(), w, http.StatusOK, core.HTML("Trash", authors.TrashPage(trashed)))
+}
+
+// RestoreAuthor takes an author out of the trash. The empty response swaps
+// away what asked for it, a trash row or the undo toast, and the
+// authors.RestoredEvent it triggers reloads the authors list.
+func (h *Handler) RestoreAuthor(w http.ResponseWriter, r *http.Request) {
+	id, ok := authorID(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	_, err := h.database.Queries().RestoreAuthor(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		h.serverError(w, "Failed to restore author", err)
+		return
+	}
+	w.Header().Set("HX-Trigger", authors.RestoredEvent)
+	w.WriteHeader(http.StatusOK)
+}
+
+// PurgeAuthor permanently deletes an author in the trash. The empty 200
+// response swaps its row away.
+func (h *Handler) PurgeAuthor(w http.ResponseWriter, r *http.Request) {
+	id, ok := authorID(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	n, err := h.database.Queries().PurgeAuthor(r.Context(), id)
+	if err != nil {
+		h.serverError(w, "Failed to purge author", err)
+		return
+	}
+	if n == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,78 @@
+package handler_test
+
+import (
+	"html"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthors_TrashAndRestore(t *testing.T) {
+	t.Parallel()
+
+	h := newDBHandler(t)
+	createAuthor(t, h, "Ada")
+	createAuthor(t, h, "Bob")
+
+	rec := serveAuthor(h.DeleteAuthor, http.MethodDelete, "/authors/1", 1, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	assert.Equal(t, []string{"Bob"}, listAuthors(t, h, "/authors").names)
+	assert.Contains(t, searchAuthors(t, h, "ada", true).Body.String(), "No authors match")
+	rec = serveAuthor(h.EditAuthor, http.MethodGet, "/authors/1/edit", 1, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = serveAuthor(h.Trash, http.MethodGet, "/authors/trash", 0, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `<tr id="author-1"`)
+	assert.NotContains(t, rec.Body.String(), "Bob")
+
+	rec = serveAuthor(h.RestoreAuthor, http.MethodPost, "/authors/1/restore", 1, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "author-restored", rec.Header().Get("HX-Trigger"))
+	assert.Empty(t, rec.Body.String())
+	assert.Equal(t, []string{"Ada", "Bob"}, listAuthors(t, h, "/authors").names)
+	assert.Contains(t, serveAuthor(h.Trash, http.MethodGet, "/authors/trash", 0, nil).Body.String(), "The trash is empty.")
+
+	rec = serveAuthor(h.RestoreAuthor, http.MethodPost, "/authors/1/restore", 1, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code, "only authors in the trash are restored")
+}
+
+func TestAuthors_Purge(t *testing.T) {
+	t.Parallel()
+
+	h := newDBHandler(t)
+	createAuthor(t, h, "Ada")
+
+	rec := serveAuthor(h.PurgeAuthor, http.MethodDelete, "/authors/trash/1", 1, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code, "only authors in the trash are purged")
+
+	rec = serveAuthor(h.DeleteAuthor, http.MethodDelete, "/authors/1", 1, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	rec = serveAuthor(h.PurgeAuthor, http.MethodDelete, "/authors/trash/1", 1, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Body.String())
+
+	rec = serveAuthor(h.RestoreAuthor, http.MethodPost, "/authors/1/restore", 1, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, serveAuthor(h.Trash, http.MethodGet, "/authors/trash", 0, nil).Body.String(), "The trash is empty.")
+}
+
+func TestAuthors_ListReloadsOnRestore(t *testing.T) {
+	t.Parallel()
+
+	h := newDBHandler(t)
+	for _, name := range []string{"Ada", "Bob", "Cy"} {
+		createAuthor(t, h, name)
+	}
+
+	body := getAuthors(t, h, "/authors?sort=created&limit=2", true).Body.String()
+	assert.Contains(t, body, `hx-get="/authors?limit=2&amp;sort=created" hx-trigger="author-restored from:body"`)
+
+	next := listAuthors(t, h, "/authors?limit=2").next
+	body = getAuthors(t, h, next, true).Body.String()
+	assert.Contains(t, body, `hx-get="`+html.EscapeString(next)+`" hx-trigger="author-restored from:body"`,
+		"the list reloads the page it shows")
+}
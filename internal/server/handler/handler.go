@@ -19,6 +19,12 @@ func New(logger *slog.Logger, database db.Database) *Handler {
 	return &Handler{logger: logger, database: database}
 }
 
+// serverError logs err and answers with a plain 500.
+func (h *Handler) serverError(w http.ResponseWriter, msg string, err error) {
+	h.logger.Error(msg, "error", err)
+	http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+}
+
 func (h *Handler) html(ctx context.Context, w http.ResponseWriter, status int, t templ.Component) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.WriteHeader(status)
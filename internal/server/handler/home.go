@@ -1,21 +1,75 @@
 package handler
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"go-htmx-template/internal/components/core"
 	"go-htmx-template/internal/components/home"
 	"net/http"
-	"sync/atomic"
 )
 
-var counter atomic.Int64 //nolint:gochecknoglobals // demo counter for template
+const (
+	// homeCounter is the counter shown on the home page and incremented by
+	// POST /count.
+	homeCounter = "home"
+
+	maxCounterName = 64
+)
 
 // Home handles the home page.
 func (h *Handler) Home(w http.ResponseWriter, r *http.Request) {
-	h.html(r.Context(), w, http.StatusOK, core.HTML("Example Site", home.Page(int(counter.Load()))))
+	count, err := h.counter(r.Context(), homeCounter)
+	if err != nil {
+		h.serverError(w, "Failed to read counter", err)
+		return
+	}
+	h.html(r.Context(), w, http.StatusOK, core.HTML("Example Site", home.Page(homeCounter, count)))
 }
 
-// Count increments the counter and returns the updated Counter fragment.
+// Count increments the counter named by the {name} path value, or the home
+// counter without one, and returns the updated Counter fragment.
 func (h *Handler) Count(w http.ResponseWriter, r *http.Request) {
-	newCount := counter.Add(1)
-	h.html(r.Context(), w, http.StatusOK, home.Counter(int(newCount)))
+	name := r.PathValue("name")
+	if name == "" {
+		name = homeCounter
+	}
+	if !validCounterName(name) {
+		http.NotFound(w, r)
+		return
+	}
+
+	count, err := h.database.Queries().IncrementCounter(r.Context(), name)
+	if err != nil {
+		h.serverError(w, "Failed to increment counter", err)
+		return
+	}
+	h.html(r.Context(), w, http.StatusOK, home.Counter(name, int(count)))
+}
+
+// counter returns the value of the named counter; one never incremented is 0.
+func (h *Handler) counter(ctx context.Context, name string) (int, error) {
+	count, err := h.database.Queries().GetCounter(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("reading counter %s: %w", name, err)
+	}
+	return int(count), nil
+}
+
+// validCounterName limits counter names to short slugs, since anyone can
+// create a counter by incrementing it.
+func validCounterName(name string) bool {
+	if name == "" || len(name) > maxCounterName {
+		return false
+	}
+	for _, c := range []byte(name) {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' && c != '_' {
+			return false
+		}
+	}
+	return true
 }
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
@@ -12,17 +13,23 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"go-htmx-template/internal/db"
 	"go-htmx-template/internal/server/handler"
 )
 
-func newHomeHandler() *handler.Handler {
-	return handler.New(slog.New(slog.DiscardHandler), nil)
+func newDBHandler(t *testing.T) *handler.Handler {
+	t.Helper()
+	database, err := db.New(filepath.Join(t.TempDir(), "db.sqlite3"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = database.Close() })
+	require.NoError(t, db.Migrate(t.Context(), database, db.Up, 0))
+	return handler.New(slog.New(slog.DiscardHandler), database)
 }
 
 func TestHome_StatusCode(t *testing.T) {
 	t.Parallel()
 
-	h := newHomeHandler()
+	h := newDBHandler(t)
 	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/", nil)
 	rec := httptest.NewRecorder()
 
@@ -34,7 +41,7 @@ func TestHome_StatusCode(t *testing.T) {
 func TestHome_ContentType(t *testing.T) {
 	t.Parallel()
 
-	h := newHomeHandler()
+	h := newDBHandler(t)
 	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/", nil)
 	rec := httptest.NewRecorder()
 
@@ -46,7 +53,7 @@ func TestHome_ContentType(t *testing.T) {
 func TestHome_ContainsWelcomeText(t *testing.T) {
 	t.Parallel()
 
-	h := newHomeHandler()
+	h := newDBHandler(t)
 	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/", nil)
 	rec := httptest.NewRecorder()
 
@@ -58,7 +65,7 @@ func TestHome_ContainsWelcomeText(t *testing.T) {
 func TestCount_StatusCode(t *testing.T) {
 	t.Parallel()
 
-	h := newHomeHandler()
+	h := newDBHandler(t)
 	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/count", nil)
 	rec := httptest.NewRecorder()
 
@@ -70,7 +77,7 @@ func TestCount_StatusCode(t *testing.T) {
 func TestCount_ContentType(t *testing.T) {
 	t.Parallel()
 
-	h := newHomeHandler()
+	h := newDBHandler(t)
 	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/count", nil)
 	rec := httptest.NewRecorder()
 
@@ -82,20 +89,21 @@ func TestCount_ContentType(t *testing.T) {
 func TestCount_ContainsCounterFragment(t *testing.T) {
 	t.Parallel()
 
-	h := newHomeHandler()
+	h := newDBHandler(t)
 	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/count", nil)
 	rec := httptest.NewRecorder()
 
 	h.Count(rec, req)
 
 	body := rec.Body.String()
-	assert.Contains(t, body, `id="counter"`)
+	assert.Contains(t, body, `id="counter-home"`)
 	assert.Contains(t, body, "Count: ")
 }
 
-//nolint:paralleltest // shares package-level counter; two sequential calls must not be interleaved
 func TestCount_IncrementsOnEachCall(t *testing.T) {
-	h := newHomeHandler()
+	t.Parallel()
+
+	h := newDBHandler(t)
 
 	req1 := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/count", nil)
 	rec1 := httptest.NewRecorder()
@@ -111,6 +119,40 @@ func TestCount_IncrementsOnEachCall(t *testing.T) {
 	require.True(t, ok1, "could not extract count from first response")
 	require.True(t, ok2, "could not extract count from second response")
 	assert.Equal(t, count1+1, count2, "each call should increment the counter by 1")
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	h.Home(rec, req)
+	count, ok := extractCountFromBody(rec.Body.String())
+	require.True(t, ok, "could not extract count from the home page")
+	assert.Equal(t, count2, count, "the home page shows the stored count")
+}
+
+func TestCount_NamedCounters(t *testing.T) {
+	t.Parallel()
+
+	h := newDBHandler(t)
+	count := func(name string) *httptest.ResponseRecorder {
+		req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/count/"+name, nil)
+		req.SetPathValue("name", name)
+		rec := httptest.NewRecorder()
+		h.Count(rec, req)
+		return rec
+	}
+
+	count("likes")
+	rec := count("likes")
+	assert.Contains(t, rec.Body.String(), `id="counter-likes"`)
+	n, ok := extractCountFromBody(rec.Body.String())
+	require.True(t, ok)
+	assert.Equal(t, 2, n)
+
+	n, ok = extractCountFromBody(count("views").Body.String())
+	require.True(t, ok)
+	assert.Equal(t, 1, n, "counters are independent")
+
+	assert.Equal(t, http.StatusNotFound, count("Not%20Valid").Code)
+	assert.Equal(t, http.StatusNotFound, count(strings.Repeat("a", 65)).Code)
 }
 
 // extractCountFromBody parses the integer after "Count: " in the response body.
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"encoding/json"
+	"go-htmx-template/internal/log"
+	"log/slog"
+	"net/http"
+)
+
+const maxLogLevelBody = 1 << 10
+
+// LogLevel returns a handler that reports the current log level on GET and
+// changes it on PUT with a body such as {"level":"debug"}.
+func (h *Handler) LogLevel(level *slog.LevelVar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			var req logLevelBody
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxLogLevelBody)).Decode(&req); err != nil {
+				http.Error(w, "invalid JSON body", http.StatusBadRequest)
+				return
+			}
+			parsed, err := log.ParseLevel(req.Level)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			previous := log.FromSlog(level.Level())
+			level.Set(parsed.ToSlog())
+			h.logger.Info("log level changed", "from", previous, "to", parsed)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(logLevelBody{Level: string(log.FromSlog(level.Level()))}); err != nil {
+			h.logger.Error("failed to encode log level response", "error", err)
+		}
+	}
+}
+
+type logLevelBody struct {
+	Level string `json:"level"`
+}
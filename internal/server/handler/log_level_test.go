@@ -0,0 +1,62 @@
+package handler_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-htmx-template/internal/server/handler"
+)
+
+func TestLogLevel_Get(t *testing.T) {
+	t.Parallel()
+
+	level := new(slog.LevelVar)
+	level.Set(slog.LevelWarn)
+	h := handler.New(slog.New(slog.DiscardHandler), nil)
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/log/level", nil)
+	rec := httptest.NewRecorder()
+	h.LogLevel(level)(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"level":"warn"}`, rec.Body.String())
+}
+
+func TestLogLevel_Put(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		body     string
+		status   int
+		expected slog.Level
+	}{
+		{name: "changes level", body: `{"level":"debug"}`, status: http.StatusOK, expected: slog.LevelDebug},
+		{name: "is case insensitive", body: `{"level":"ERROR"}`, status: http.StatusOK, expected: slog.LevelError},
+		{name: "rejects unknown level", body: `{"level":"verbose"}`, status: http.StatusBadRequest, expected: slog.LevelInfo},
+		{name: "rejects invalid JSON", body: `level=debug`, status: http.StatusBadRequest, expected: slog.LevelInfo},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			level := new(slog.LevelVar)
+			h := handler.New(slog.New(slog.DiscardHandler), nil)
+
+			req := httptest.NewRequestWithContext(context.Background(), http.MethodPut, "/log/level", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			h.LogLevel(level)(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, tt.expected, level.Level())
+		})
+	}
+}
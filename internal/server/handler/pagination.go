@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+var (
+	errInvalidCursor   = errors.New("invalid cursor")
+	errInvalidPageSize = errors.New("invalid page size")
+)
+
+// cursor is a keyset position: the sort value and id of the row a page
+// continues from. Rows inserted before it do not shift later pages, unlike
+// with an offset.
+type cursor struct {
+	value string
+	id    int64
+}
+
+// String encodes the cursor for a URL.
+func (c cursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.value)) + "." + strconv.FormatInt(c.id, 10)
+}
+
+func parseCursor(s string) (cursor, error) {
+	value, id, ok := strings.Cut(s, ".")
+	if !ok {
+		return cursor{}, errInvalidCursor
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor{}, errInvalidCursor
+	}
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return cursor{}, errInvalidCursor
+	}
+	return cursor{value: string(decoded), id: n}, nil
+}
+
+// pageRequest is where a page starts: at the beginning, after a cursor or,
+// going back, before one.
+type pageRequest struct {
+	after  *cursor
+	before *cursor
+	limit  int
+}
+
+// parsePageRequest reads the after, before and limit query parameters.
+func parsePageRequest(r *http.Request) (pageRequest, error) {
+	query := r.URL.Query()
+	page := pageRequest{limit: defaultPageSize}
+	if raw := query.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxPageSize {
+			return pageRequest{}, errInvalidPageSize
+		}
+		page.limit = n
+	}
+	var err error
+	if page.after, err = cursorParam(query, "after"); err != nil {
+		return pageRequest{}, err
+	}
+	if page.before, err = cursorParam(query, "before"); err != nil {
+		return pageRequest{}, err
+	}
+	if page.after != nil && page.before != nil {
+		return pageRequest{}, errInvalidCursor
+	}
+	return page, nil
+}
+
+func cursorParam(query url.Values, name string) (*cursor, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return nil, nil //nolint:nilnil // an absent cursor is not an error
+	}
+	c, err := parseCursor(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// isFragmentRequest reports whether htmx asked for part of a page. History
+// restores, after a reload or with a cold cache, need the whole page.
+func isFragmentRequest(r *http.Request) bool {
+	return r.Header.Get("HX-Request") == "true" && r.Header.Get("HX-History-Restore-Request") != "true"
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"encoding/json"
+	"go-htmx-template/internal/health"
+	"net/http"
+)
+
+// Ready returns a handler that runs the readiness checks and responds with
+// each check's name and status: 200 when every check passes, 503 otherwise or
+// once the server has started draining. Why a check failed is logged, not
+// returned; ReadyDetail returns it.
+func (h *Handler) Ready(readiness *health.Readiness) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := readiness.Check(r.Context())
+		for name, result := range report.Checks {
+			if result.Status != health.StatusOK {
+				h.logger.WarnContext(r.Context(), "readiness check failed", "check", name, "error", result.Error)
+			}
+		}
+		h.writeReport(w, report.Redacted())
+	}
+}
+
+// ReadyDetail is like Ready but includes each check's error and duration, for
+// the admin listener.
+func (h *Handler) ReadyDetail(readiness *health.Readiness) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.writeReport(w, readiness.Check(r.Context()))
+	}
+}
+
+func (h *Handler) writeReport(w http.ResponseWriter, report health.Report) {
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		h.logger.Error("failed to encode readiness response", "error", err)
+	}
+}
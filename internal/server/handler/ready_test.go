@@ -0,0 +1,74 @@
+package handler_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-htmx-template/internal/health"
+	"go-htmx-template/internal/server/handler"
+)
+
+func TestReady(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		checkErr error
+		draining bool
+		detail   bool
+		status   int
+		expected string
+		hidden   string
+	}{
+		{name: "ready", status: http.StatusOK, expected: `"database":{"status":"ok"}`, hidden: "duration"},
+		{
+			name:     "failing check",
+			checkErr: errors.New("dial /var/run/db.sock: refused"),
+			status:   http.StatusServiceUnavailable,
+			expected: `"database":{"status":"error"}`,
+			hidden:   "db.sock",
+		},
+		{
+			name:     "failing check with detail",
+			checkErr: errors.New("down"),
+			detail:   true,
+			status:   http.StatusServiceUnavailable,
+			expected: `"error":"down"`,
+		},
+		{name: "draining", draining: true, status: http.StatusServiceUnavailable, expected: `"status":"draining"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			readiness := health.NewReadiness()
+			readiness.Register("database", health.CheckerFunc(func(context.Context) error { return tt.checkErr }), 0)
+			if tt.draining {
+				readiness.SetDraining()
+			}
+			h := handler.New(slog.New(slog.DiscardHandler), nil)
+
+			req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/readyz", nil)
+			rec := httptest.NewRecorder()
+			if tt.detail {
+				h.ReadyDetail(readiness)(rec, req)
+			} else {
+				h.Ready(readiness)(rec, req)
+			}
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			assert.Contains(t, rec.Body.String(), tt.expected)
+			if tt.hidden != "" {
+				assert.NotContains(t, rec.Body.String(), tt.hidden)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+//go:build !unix
+
+package server
+
+import (
+	"errors"
+	"net"
+	"os"
+)
+
+var errHandoffUnsupported = errors.New("listener handoff is not supported on this platform")
+
+// notifyRestart returns a channel that never fires; listener handoff needs
+// descriptor inheritance, which is only available on unix.
+func notifyRestart() (<-chan os.Signal, func()) {
+	return nil, func() {}
+}
+
+func inheritedListeners() ([]net.Listener, error) {
+	return nil, nil
+}
+
+func notifyHandoffReady() error {
+	return nil
+}
+
+func (s *Server) handoff() error {
+	return errHandoffUnsupported
+}
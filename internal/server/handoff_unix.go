@@ -0,0 +1,252 @@
+//go:build unix
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// listenFDsStart is the first inherited file descriptor, as defined by
+	// systemd's sd_listen_fds(3).
+	listenFDsStart = 3
+
+	envListenFDs     = "LISTEN_FDS"
+	envListenPID     = "LISTEN_PID"
+	envListenFDNames = "LISTEN_FDNAMES"
+	// envHandoffParent is set by a restarting parent to its own PID. It takes
+	// the place of LISTEN_PID, which the parent cannot know before forking.
+	envHandoffParent = "SERVER_HANDOFF_PARENT"
+	// envHandoffReadyFD is the descriptor the child writes to once it serves.
+	envHandoffReadyFD = "SERVER_HANDOFF_READY_FD"
+
+	handoffTimeout = 30 * time.Second
+)
+
+var errHandoffTimeout = errors.New("child did not become ready in time")
+
+// notifyRestart returns a channel that receives SIGUSR2, which asks the server
+// to hand its listeners to a freshly executed copy of itself.
+func notifyRestart() (<-chan os.Signal, func()) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR2)
+	return ch, func() { signal.Stop(ch) }
+}
+
+// inheritedListeners returns the listeners passed to this process by systemd
+// socket activation or by a restarting parent, in descriptor order. The
+// environment variables are cleared so they do not leak into children.
+func inheritedListeners() ([]net.Listener, error) {
+	n, err := strconv.Atoi(os.Getenv(envListenFDs))
+	if err != nil || n <= 0 {
+		return nil, nil
+	}
+
+	forSystemd := os.Getenv(envListenPID) == strconv.Itoa(os.Getpid())
+	fromParent := os.Getenv(envHandoffParent) == strconv.Itoa(os.Getppid())
+	for _, key := range []string{envListenFDs, envListenPID, envListenFDNames, envHandoffParent} {
+		_ = os.Unsetenv(key)
+	}
+	if !forSystemd && !fromParent {
+		return nil, nil
+	}
+
+	return listenersFromFDs(listenFDsStart, n)
+}
+
+func listenersFromFDs(start, n int) ([]net.Listener, error) {
+	listeners := make([]net.Listener, 0, n)
+	for fd := start; fd < start+n; fd++ {
+		f := os.NewFile(uintptr(fd), "listener-"+strconv.Itoa(fd))
+		// FileListener dups the descriptor, so the original is closed either way.
+		ln, err := net.FileListener(f)
+		_ = f.Close()
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+			return nil, fmt.Errorf("fd %d: %w", fd, err)
+		}
+		listeners = append(listeners, ln)
+	}
+	return listeners, nil
+}
+
+// notifyHandoffReady tells a restarting parent that this process is serving,
+// so the parent can begin draining. It is a no-op when not started by a
+// handoff.
+func notifyHandoffReady() error {
+	fdStr := os.Getenv(envHandoffReadyFD)
+	if fdStr == "" {
+		return nil
+	}
+	_ = os.Unsetenv(envHandoffReadyFD)
+
+	fd, err := strconv.Atoi(fdStr)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %w", envHandoffReadyFD, fdStr, err)
+	}
+
+	f := os.NewFile(uintptr(fd), "handoff-ready")
+	defer f.Close()
+	if _, err = f.Write([]byte{1}); err != nil {
+		return fmt.Errorf("notifying parent: %w", err)
+	}
+	return nil
+}
+
+type filer interface {
+	File() (*os.File, error)
+}
+
+// handoff re-executes the running binary with the server's listeners and
+// waits until the child reports it is serving. On failure the child is killed
+// and this process keeps serving.
+func (s *Server) handoff() error {
+	files, err := s.listenerFiles()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("creating ready pipe: %w", err)
+	}
+	defer readyR.Close()
+
+	exe, err := os.Executable()
+	if err != nil {
+		_ = readyW.Close()
+		return fmt.Errorf("resolving executable: %w", err)
+	}
+
+	//nolint:gosec // re-executing our own binary with our own arguments
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = append(files, readyW)
+	cmd.Env = append(handoffEnv(),
+		envListenFDs+"="+strconv.Itoa(len(files)),
+		envHandoffParent+"="+strconv.Itoa(os.Getpid()),
+		envHandoffReadyFD+"="+strconv.Itoa(listenFDsStart+len(files)),
+	)
+
+	err = cmd.Start()
+	_ = readyW.Close()
+	if err != nil {
+		return fmt.Errorf("starting child: %w", err)
+	}
+	s.logger.Info("started child process for handoff", "pid", cmd.Process.Pid)
+
+	ready := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1)
+		_, rerr := readyR.Read(buf)
+		ready <- rerr
+	}()
+
+	// Reap the child if it exits; once ready, this process exits first anyway.
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	timer := time.NewTimer(handoffTimeout)
+	defer timer.Stop()
+
+	select {
+	case err = <-ready:
+		if err == nil {
+			s.keepSocketFiles()
+			return nil
+		}
+		err = fmt.Errorf("child closed ready pipe: %w", err)
+	case werr := <-exited:
+		return fmt.Errorf("child exited before becoming ready: %w", werr)
+	case <-timer.C:
+		err = errHandoffTimeout
+	}
+
+	_ = cmd.Process.Kill()
+	return err
+}
+
+// listenerFiles duplicates the bound listeners in the order the child will
+// inherit them: one per address, then the redirect and admin listeners.
+func (s *Server) listenerFiles() ([]*os.File, error) {
+	listeners := s.allListeners()
+
+	files := make([]*os.File, 0, len(listeners))
+	closeFiles := func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}
+	for _, ln := range listeners {
+		fl, ok := ln.(filer)
+		if !ok {
+			closeFiles()
+			return nil, fmt.Errorf("listener %s cannot be handed off", ln.Addr())
+		}
+		f, err := fl.File()
+		if err != nil {
+			closeFiles()
+			return nil, fmt.Errorf("duplicating listener %s: %w", ln.Addr(), err)
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+func (s *Server) allListeners() []net.Listener {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	listeners := append([]net.Listener(nil), s.lns...)
+	if s.redirectLn != nil {
+		listeners = append(listeners, s.redirectLn)
+	}
+	if s.adminLn != nil {
+		listeners = append(listeners, s.adminLn)
+	}
+	return listeners
+}
+
+// keepSocketFiles stops this process from unlinking unix socket files when it
+// shuts down, since the child is now serving on them.
+func (s *Server) keepSocketFiles() {
+	for _, ln := range s.allListeners() {
+		if ul, ok := ln.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(false)
+		}
+	}
+}
+
+// handoffEnv returns the current environment without any inherited listener
+// variables.
+func handoffEnv() []string {
+	env := os.Environ()
+	out := env[:0:0]
+	for _, kv := range env {
+		key, _, _ := strings.Cut(kv, "=")
+		switch key {
+		case envListenFDs, envListenPID, envListenFDNames, envHandoffParent, envHandoffReadyFD:
+			continue
+		}
+		out = append(out, kv)
+	}
+	return out
+}
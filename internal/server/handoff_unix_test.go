@@ -0,0 +1,169 @@
+//go:build unix
+
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dupFD returns a raw descriptor for f that no *os.File owns, mirroring what a
+// process sees for descriptors it inherited.
+func dupFD(t *testing.T, f *os.File) int {
+	t.Helper()
+	fd, err := syscall.Dup(int(f.Fd()))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	return fd
+}
+
+func TestListenersFromFDs(t *testing.T) {
+	t.Parallel()
+
+	var lc net.ListenConfig
+	ln, err := lc.Listen(t.Context(), "tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	f, err := ln.(*net.TCPListener).File()
+	require.NoError(t, err)
+	fd := dupFD(t, f)
+
+	listeners, err := listenersFromFDs(fd, 1)
+	require.NoError(t, err)
+	require.Len(t, listeners, 1)
+	t.Cleanup(func() { _ = listeners[0].Close() })
+
+	assert.Equal(t, ln.Addr().String(), listeners[0].Addr().String())
+
+	accepted := make(chan error, 1)
+	go func() {
+		conn, aerr := listeners[0].Accept()
+		if aerr == nil {
+			_ = conn.Close()
+		}
+		accepted <- aerr
+	}()
+
+	// Both listeners share one socket, and only the inherited one is accepting.
+	var d net.Dialer
+	conn, err := d.DialContext(t.Context(), "tcp", ln.Addr().String())
+	require.NoError(t, err)
+	_ = conn.Close()
+	require.NoError(t, <-accepted)
+}
+
+func TestListenersFromFDs_InvalidDescriptor(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.CreateTemp(t.TempDir(), "not-a-socket")
+	require.NoError(t, err)
+	fd := dupFD(t, f)
+
+	_, err = listenersFromFDs(fd, 1)
+	require.Error(t, err)
+}
+
+func TestInheritedListeners_IgnoresOtherProcess(t *testing.T) {
+	t.Setenv(envListenFDs, "1")
+	t.Setenv(envListenPID, strconv.Itoa(os.Getpid()+1))
+
+	listeners, err := inheritedListeners()
+	require.NoError(t, err)
+	assert.Empty(t, listeners)
+	assert.Empty(t, os.Getenv(envListenFDs), "variables should be cleared")
+	assert.Empty(t, os.Getenv(envListenPID), "variables should be cleared")
+}
+
+func TestNotifyHandoffReady(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = r.Close() })
+
+	t.Setenv(envHandoffReadyFD, strconv.Itoa(dupFD(t, w)))
+	require.NoError(t, notifyHandoffReady())
+
+	buf := make([]byte, 1)
+	n, err := r.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Empty(t, os.Getenv(envHandoffReadyFD))
+}
+
+func TestNotifyHandoffReady_NotAChild(t *testing.T) {
+	t.Setenv(envHandoffReadyFD, "")
+	require.NoError(t, notifyHandoffReady())
+}
+
+func TestHandoffEnv_StripsListenerVariables(t *testing.T) {
+	t.Setenv(envListenFDs, "2")
+	t.Setenv(envHandoffParent, "1")
+	t.Setenv("KEEP_ME", "yes")
+
+	env := handoffEnv()
+	assert.Contains(t, env, "KEEP_ME=yes")
+	for _, kv := range env {
+		assert.NotContains(t, kv, envListenFDs+"=")
+		assert.NotContains(t, kv, envHandoffParent+"=")
+	}
+}
+
+func TestAwaitShutdown_SecondRestartAfterHandoff(t *testing.T) {
+	t.Parallel()
+
+	// Both signals are queued when the handoff succeeds, so the second one
+	// races the handoff being noticed. Repeat to see both orders.
+	for range 50 {
+		s := New(slog.New(slog.DiscardHandler), ":0")
+		var calls atomic.Int32
+		s.handoffFn = func() error {
+			calls.Add(1)
+			return nil
+		}
+
+		restart := make(chan os.Signal, 2)
+		restart <- syscall.SIGUSR2
+		restart <- syscall.SIGUSR2
+		require.NoError(t, s.awaitShutdown(nil, restart))
+		assert.Equal(t, int32(1), calls.Load())
+	}
+}
+
+func TestAwaitShutdown_RestartAfterFailedHandoff(t *testing.T) {
+	t.Parallel()
+
+	s := New(slog.New(slog.DiscardHandler), ":0")
+	var calls atomic.Int32
+	s.handoffFn = func() error {
+		if calls.Add(1) == 1 {
+			return errors.New("child did not start")
+		}
+		return nil
+	}
+
+	restart := make(chan os.Signal, 1)
+	done := make(chan error, 1)
+	go func() { done <- s.awaitShutdown(nil, restart) }()
+
+	// Signals sent while the failed handoff winds down are ignored, so keep
+	// sending until the retry starts.
+	require.Eventually(t, func() bool {
+		select {
+		case restart <- syscall.SIGUSR2:
+		default:
+		}
+		return calls.Load() == 2
+	}, 5*time.Second, time.Millisecond)
+	require.NoError(t, <-done)
+	assert.Equal(t, int32(2), calls.Load(), "a failed handoff can be retried")
+}
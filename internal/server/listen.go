@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	unixScheme        = "unix://"
+	defaultSocketMode = 0o660
+	staleProbeTimeout = time.Second
+)
+
+var (
+	errSocketInUse = errors.New("socket is in use by another process")
+	errNotSocket   = errors.New("path exists and is not a socket")
+)
+
+// listen binds addr. Addresses prefixed with unix:// are bound as unix domain
+// sockets, replacing a stale socket file left behind by a previous process.
+func (s *Server) listen(ctx context.Context, addr string) (net.Listener, error) {
+	var lc net.ListenConfig
+
+	path, isUnix := strings.CutPrefix(addr, unixScheme)
+	if !isUnix {
+		ln, err := lc.Listen(ctx, "tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("listening on %s: %w", addr, err)
+		}
+		return ln, nil
+	}
+
+	if err := removeStaleSocket(ctx, path); err != nil {
+		return nil, err
+	}
+
+	ln, err := lc.Listen(ctx, "unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("listening on %s: %w", addr, err)
+	}
+	if err = os.Chmod(path, s.socketMode); err != nil {
+		_ = ln.Close()
+		return nil, fmt.Errorf("setting mode on %s: %w", path, err)
+	}
+	return ln, nil
+}
+
+// removeStaleSocket deletes the socket at path if no process is accepting on
+// it. Anything that is not a socket is left alone.
+func removeStaleSocket(ctx context.Context, path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("checking %s: %w", path, err)
+	}
+	if info.Mode()&fs.ModeSocket == 0 {
+		return fmt.Errorf("%w: %s", errNotSocket, path)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, staleProbeTimeout)
+	defer cancel()
+
+	var d net.Dialer
+	if conn, derr := d.DialContext(ctx, "unix", path); derr == nil {
+		_ = conn.Close()
+		return fmt.Errorf("%w: %s", errSocketInUse, path)
+	}
+
+	if err = os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("removing stale socket %s: %w", path, err)
+	}
+	return nil
+}
+
+// firstTCPAddr returns the address of the first TCP listener, which is where
+// HTTP requests are redirected to. It is empty if there is none.
+func firstTCPAddr(lns []net.Listener) string {
+	for _, ln := range lns {
+		if addr, ok := ln.Addr().(*net.TCPAddr); ok {
+			return addr.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// NoDeadline removes a deadline entirely when passed to Deadlines.
+const NoDeadline time.Duration = -1
+
+// Deadlines returns a middleware that overrides the server's read and write
+// deadlines for the routes it wraps, for long-running endpoints such as
+// uploads or streaming responses. Each deadline is measured from when the
+// request reaches the middleware; zero keeps the server's deadline and
+// NoDeadline removes it.
+func Deadlines(logger *slog.Logger, read, write time.Duration) Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rc := http.NewResponseController(w)
+			if err := setDeadline(rc.SetReadDeadline, read); err != nil {
+				logDeadlineError(logger, r, "read", err)
+			}
+			if err := setDeadline(rc.SetWriteDeadline, write); err != nil {
+				logDeadlineError(logger, r, "write", err)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// LongRequests names the routes that may take longer than the server's read
+// and write timeouts.
+type LongRequests struct {
+	// Paths are path prefixes such as /uploads/.
+	Paths []string
+	// Timeout replaces both deadlines on those paths; zero removes them.
+	Timeout time.Duration
+}
+
+// Middleware returns a middleware that applies Deadlines to requests under
+// l.Paths and passes every other request through unchanged.
+func (l LongRequests) Middleware(logger *slog.Logger) Handler {
+	timeout := l.Timeout
+	if timeout == 0 {
+		timeout = NoDeadline
+	}
+	return func(next http.Handler) http.Handler {
+		if len(l.Paths) == 0 {
+			return next
+		}
+		long := Deadlines(logger, timeout, timeout)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, prefix := range l.Paths {
+				if strings.HasPrefix(r.URL.Path, prefix) {
+					long.ServeHTTP(w, r)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func setDeadline(set func(time.Time) error, d time.Duration) error {
+	switch {
+	case d == 0:
+		return nil
+	case d < 0:
+		return set(time.Time{})
+	default:
+		return set(time.Now().Add(d))
+	}
+}
+
+func logDeadlineError(logger *slog.Logger, r *http.Request, kind string, err error) {
+	level := slog.LevelError
+	if errors.Is(err, http.ErrNotSupported) {
+		level = slog.LevelWarn
+	}
+	logger.Log(r.Context(), level, "failed to set "+kind+" deadline",
+		slog.String("path", r.URL.Path),
+		slog.Any("error", err),
+	)
+}
@@ -0,0 +1,64 @@
+package middleware_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go-htmx-template/internal/server/middleware"
+)
+
+func TestDeadlines(t *testing.T) {
+	t.Parallel()
+
+	logger := slog.New(slog.DiscardHandler)
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(150 * time.Millisecond)
+		_, _ = io.WriteString(w, "done")
+	})
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		ok      bool
+	}{
+		{name: "server write timeout applies", handler: slow, ok: false},
+		{name: "extended write deadline", handler: middleware.Deadlines(logger, 0, time.Second)(slow), ok: true},
+		{name: "removed write deadline", handler: middleware.Deadlines(logger, 0, middleware.NoDeadline)(slow), ok: true},
+		{
+			name:    "through a wrapping middleware",
+			handler: middleware.Logging(logger, middleware.IPConfig{})(middleware.Deadlines(logger, 0, time.Second)(slow)),
+			ok:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewUnstartedServer(tt.handler)
+			srv.Config.WriteTimeout = 50 * time.Millisecond
+			srv.Start()
+			t.Cleanup(srv.Close)
+
+			req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, srv.URL, nil)
+			require.NoError(t, err)
+			resp, err := srv.Client().Do(req)
+			if !tt.ok {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, "done", string(body))
+		})
+	}
+}
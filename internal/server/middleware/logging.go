@@ -4,23 +4,31 @@ import (
 	"log/slog"
 	"net/http"
 	"time"
+
+	"go-htmx-template/internal/querystats"
 )
 
-// Logging returns a middleware handler that logs requests.
+// Logging returns a middleware handler that logs requests, with the number
+// of database queries each ran and the time spent in them.
 func Logging(logger *slog.Logger, ipCfg IPConfig) Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
 			rw := newResponseWriter(w)
-			next.ServeHTTP(rw, r)
+			requestID := GetRequestID(r.Context())
+			ctx, stats := querystats.NewContext(r.Context(), requestID)
+			next.ServeHTTP(rw, r.WithContext(ctx))
 			logger.Debug(
 				"Handled request",
+				slog.String("request_id", requestID),
 				slog.String("method", r.Method),
 				slog.String("path", r.URL.Path),
 				slog.String("remote", GetClientIP(r, ipCfg)),
 				slog.Int("status", rw.statusCode),
 				slog.Int("bytes", rw.bytesWritten),
 				slog.Duration("duration", time.Since(start)),
+				slog.Int("queries", stats.Count()),
+				slog.Duration("db_time", stats.Duration()),
 			)
 		})
 	}
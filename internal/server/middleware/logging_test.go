@@ -99,6 +99,8 @@ func TestLogging_Middleware(t *testing.T) {
 				"status=200",
 				"bytes=8",
 				"duration=",
+				"queries=0",
+				"db_time=0s",
 			},
 		},
 	}
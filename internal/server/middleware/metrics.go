@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics records request counts and latencies and serves them, together with
+// a few runtime gauges, in the Prometheus text format. Requests are labelled by
+// method and status code only, to keep cardinality bounded.
+type Metrics struct {
+	start    time.Time
+	inFlight atomic.Int64
+
+	mu       sync.Mutex
+	requests map[requestKey]*requestStats
+}
+
+type requestKey struct {
+	method string
+	code   int
+}
+
+type requestStats struct {
+	count   uint64
+	seconds float64
+}
+
+// NewMetrics creates an empty Metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{start: time.Now(), requests: make(map[requestKey]*requestStats)}
+}
+
+// Middleware returns a middleware that records every request in m.
+func (m *Metrics) Middleware() Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			m.inFlight.Add(1)
+			defer m.inFlight.Add(-1)
+
+			rw := newResponseWriter(w)
+			next.ServeHTTP(rw, r)
+
+			m.observe(metricsMethod(r.Method), rw.statusCode, time.Since(start))
+		})
+	}
+}
+
+func (m *Metrics) observe(method string, code int, d time.Duration) {
+	key := requestKey{method: method, code: code}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats, ok := m.requests[key]
+	if !ok {
+		stats = &requestStats{}
+		m.requests[key] = stats
+	}
+	stats.count++
+	stats.seconds += d.Seconds()
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.write(w)
+}
+
+func (m *Metrics) write(w io.Writer) {
+	type row struct {
+		key   requestKey
+		stats requestStats
+	}
+
+	m.mu.Lock()
+	rows := make([]row, 0, len(m.requests))
+	for k, v := range m.requests {
+		rows = append(rows, row{key: k, stats: *v})
+	}
+	m.mu.Unlock()
+
+	slices.SortFunc(rows, func(a, b row) int {
+		return cmp.Or(cmp.Compare(a.key.method, b.key.method), cmp.Compare(a.key.code, b.key.code))
+	})
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	_, _ = fmt.Fprintln(w, "# HELP http_request_duration_seconds Time spent serving HTTP requests.")
+	_, _ = fmt.Fprintln(w, "# TYPE http_request_duration_seconds summary")
+	for _, r := range rows {
+		labels := fmt.Sprintf(`{method=%q,code="%d"}`, r.key.method, r.key.code)
+		_, _ = fmt.Fprintf(w, "http_request_duration_seconds_sum%s %s\n", labels, formatFloat(r.stats.seconds))
+		_, _ = fmt.Fprintf(w, "http_request_duration_seconds_count%s %d\n", labels, r.stats.count)
+	}
+
+	writeGauge(w, "http_requests_in_flight", "HTTP requests currently being served.", float64(m.inFlight.Load()))
+	writeGauge(w, "process_uptime_seconds", "Seconds since the process started serving.", time.Since(m.start).Seconds())
+	writeGauge(w, "go_goroutines", "Number of goroutines that currently exist.", float64(runtime.NumGoroutine()))
+	writeGauge(w, "go_memstats_heap_alloc_bytes", "Bytes of allocated heap objects.", float64(mem.HeapAlloc))
+	writeGauge(w, "go_memstats_sys_bytes", "Bytes of memory obtained from the OS.", float64(mem.Sys))
+}
+
+func writeGauge(w io.Writer, name, help string, value float64) {
+	_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", name, help, name, name, formatFloat(value))
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// metricsMethod maps non-standard methods to a single label value so clients
+// cannot create unbounded series.
+func metricsMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions, http.MethodConnect, http.MethodTrace:
+		return method
+	default:
+		return "OTHER"
+	}
+}
@@ -0,0 +1,89 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-htmx-template/internal/server/middleware"
+)
+
+func scrape(t *testing.T, m *middleware.Metrics) string {
+	t.Helper()
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/metrics", nil)
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+	return rec.Body.String()
+}
+
+func TestMetrics_CountsByMethodAndStatus(t *testing.T) {
+	t.Parallel()
+
+	m := middleware.NewMetrics()
+	handler := m.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	for _, tc := range []struct{ method, path string }{
+		{http.MethodGet, "/"},
+		{http.MethodGet, "/"},
+		{http.MethodPost, "/count"},
+		{http.MethodGet, "/missing"},
+	} {
+		req := httptest.NewRequestWithContext(context.Background(), tc.method, tc.path, nil)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `http_request_duration_seconds_count{method="GET",code="200"} 2`)
+	assert.Contains(t, body, `http_request_duration_seconds_count{method="POST",code="200"} 1`)
+	assert.Contains(t, body, `http_request_duration_seconds_count{method="GET",code="404"} 1`)
+	assert.Contains(t, body, "# TYPE http_request_duration_seconds summary")
+}
+
+func TestMetrics_NonStandardMethodsShareALabel(t *testing.T) {
+	t.Parallel()
+
+	m := middleware.NewMetrics()
+	handler := m.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, method := range []string{"FOO", "BAR"} {
+		req := httptest.NewRequestWithContext(context.Background(), method, "/", nil)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `http_request_duration_seconds_count{method="OTHER",code="200"} 2`)
+	assert.NotContains(t, body, "FOO")
+}
+
+func TestMetrics_InFlightGauge(t *testing.T) {
+	t.Parallel()
+
+	m := middleware.NewMetrics()
+	var during string
+	handler := m.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		during = scrape(t, m)
+	}))
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Contains(t, during, "http_requests_in_flight 1\n")
+	assert.Contains(t, scrape(t, m), "http_requests_in_flight 0\n")
+}
+
+func TestMetrics_RuntimeGauges(t *testing.T) {
+	t.Parallel()
+
+	body := scrape(t, middleware.NewMetrics())
+	for _, name := range []string{"go_goroutines", "go_memstats_heap_alloc_bytes", "process_uptime_seconds"} {
+		assert.Contains(t, body, "# TYPE "+name+" gauge")
+	}
+}
@@ -24,10 +24,23 @@ const DefaultMaxEntries = 10000
 // a token bucket algorithm with in-memory storage. maxEntries caps the number
 // of IPs tracked simultaneously; use defaultMaxEntries if unsure.
 func RateLimit(ctx context.Context, logger *slog.Logger, requestsPerMinute int, maxEntries int, ipCfg IPConfig) Handler {
+	return NewRateLimiter(ctx, logger, requestsPerMinute, maxEntries, ipCfg).Middleware()
+}
+
+// RateLimiter limits requests per IP address. Unlike RateLimit, its limit can
+// be changed while it is serving.
+type RateLimiter struct {
+	rl *ipRateLimiter
+}
+
+// NewRateLimiter creates a RateLimiter that allows requestsPerMinute per IP
+// address, with bursts of the same size. Stale entries are cleaned up until
+// ctx is done.
+func NewRateLimiter(ctx context.Context, logger *slog.Logger, requestsPerMinute int, maxEntries int, ipCfg IPConfig) *RateLimiter {
 	rl := &ipRateLimiter{
 		limiters:   make(map[string]*list.Element),
 		order:      list.New(),
-		rate:       rate.Limit(float64(requestsPerMinute) / secondsPerMinute),
+		rate:       perMinute(requestsPerMinute),
 		burst:      requestsPerMinute,
 		maxEntries: maxEntries,
 		logger:     logger,
@@ -36,12 +49,37 @@ func RateLimit(ctx context.Context, logger *slog.Logger, requestsPerMinute int,
 
 	go rl.cleanupLoop(ctx)
 
+	return &RateLimiter{rl: rl}
+}
+
+// SetLimit changes the limit to requestsPerMinute for both tracked and new IP
+// addresses. Tokens already spent stay spent; the new rate applies from now.
+func (l *RateLimiter) SetLimit(requestsPerMinute int) {
+	rl := l.rl
+	now := time.Now()
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.rate = perMinute(requestsPerMinute)
+	rl.burst = requestsPerMinute
+	for _, elem := range rl.limiters {
+		if entry, ok := elem.Value.(*ipEntry); ok {
+			entry.limiter.SetLimitAt(now, rl.rate)
+			entry.limiter.SetBurstAt(now, rl.burst)
+		}
+	}
+}
+
+// Middleware returns the rate limiting middleware.
+func (l *RateLimiter) Middleware() Handler {
+	rl := l.rl
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			ip := GetClientIP(r, rl.ipCfg)
 
 			if !rl.allow(ip) {
-				logger.Warn("rate limit exceeded",
+				rl.logger.Warn("rate limit exceeded",
 					slog.String("ip", ip),
 					slog.String("method", r.Method),
 					slog.String("path", r.URL.Path),
@@ -57,6 +95,10 @@ func RateLimit(ctx context.Context, logger *slog.Logger, requestsPerMinute int,
 	}
 }
 
+func perMinute(requests int) rate.Limit {
+	return rate.Limit(float64(requests) / secondsPerMinute)
+}
+
 type ipEntry struct {
 	ip       string
 	limiter  *rate.Limiter
@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"sync"
 	"testing"
+	"time"
 
 	"go-htmx-template/internal/server/middleware"
 )
@@ -143,3 +144,40 @@ func itoa(n int) string {
 	}
 	return string(buf[pos:])
 }
+
+// TestSetLimit verifies a new limit applies to tracked and new IPs.
+func TestSetLimit(t *testing.T) {
+	t.Parallel()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	limiter := middleware.NewRateLimiter(t.Context(), slog.Default(), 1, 1000, middleware.IPConfig{})
+	h := limiter.Middleware()(ok)
+
+	if code := request(h, "1.1.1.1"); code != http.StatusOK {
+		t.Fatalf("first request: want 200, got %d", code)
+	}
+	if code := request(h, "1.1.1.1"); code != http.StatusTooManyRequests {
+		t.Fatalf("second request: want 429, got %d", code)
+	}
+
+	// 6000 per minute refills a token every 10ms.
+	limiter.SetLimit(6000)
+	time.Sleep(30 * time.Millisecond)
+	if code := request(h, "1.1.1.1"); code != http.StatusOK {
+		t.Fatalf("tracked IP after raising limit: want 200, got %d", code)
+	}
+	for i := range 3 {
+		if code := request(h, "2.2.2.2"); code != http.StatusOK {
+			t.Fatalf("new IP request %d after raising limit: want 200, got %d", i+1, code)
+		}
+	}
+
+	limiter.SetLimit(1)
+	if code := request(h, "3.3.3.3"); code != http.StatusOK {
+		t.Fatalf("new IP after lowering limit: want 200, got %d", code)
+	}
+	if code := request(h, "3.3.3.3"); code != http.StatusTooManyRequests {
+		t.Fatalf("new IP second request after lowering limit: want 429, got %d", code)
+	}
+}
@@ -16,6 +16,7 @@ func Recovery(logger *slog.Logger, onPanic http.Handler) Handler {
 						"panic recovered",
 						slog.Any("panic", err),
 						slog.String("stack", string(debug.Stack())),
+						slog.String("request_id", GetRequestID(r.Context())),
 						slog.String("method", r.Method),
 						slog.String("path", r.URL.Path),
 					)
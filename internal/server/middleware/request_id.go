@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"net/http"
+)
+
+// RequestIDHeader carries the request ID in both directions.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds an incoming request ID.
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// RequestID returns a middleware that gives each request an ID, stored in its
+// context and sent back in the X-Request-ID header. An incoming X-Request-ID
+// is kept only behind a trusted proxy, and only if it is short and made of
+// letters, digits, '-', '_', '.' and ':'.
+func RequestID(ipCfg IPConfig) Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !ipCfg.TrustProxyHeaders || !validRequestID(id) {
+				id = rand.Text()
+			}
+			w.Header().Set(RequestIDHeader, id)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+		})
+	}
+}
+
+// GetRequestID returns the ID RequestID gave the request, or "" outside it.
+func GetRequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range []byte(id) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-htmx-template/internal/server/middleware"
+)
+
+func TestRequestID(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		trust    bool
+		incoming string
+		keep     bool
+	}{
+		{name: "generated without a header", trust: true},
+		{name: "kept behind a trusted proxy", trust: true, incoming: "abc-123_x.y:z", keep: true},
+		{name: "replaced when proxy headers are not trusted", incoming: "abc-123"},
+		{name: "replaced when invalid", trust: true, incoming: "abc 123"},
+		{name: "replaced when too long", trust: true, incoming: strings.Repeat("a", 129)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var seen string
+			handler := middleware.RequestID(middleware.IPConfig{TrustProxyHeaders: tt.trust})(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					seen = middleware.GetRequestID(r.Context())
+				}),
+			)
+
+			req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(middleware.RequestIDHeader, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.NotEmpty(t, seen)
+			assert.Equal(t, seen, rec.Header().Get(middleware.RequestIDHeader))
+			if tt.keep {
+				assert.Equal(t, tt.incoming, seen)
+			} else {
+				assert.NotEqual(t, tt.incoming, seen)
+			}
+		})
+	}
+}
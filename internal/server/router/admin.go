@@ -0,0 +1,43 @@
+package router
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+
+	"go-htmx-template/internal/health"
+	"go-htmx-template/internal/server/handler"
+	"go-htmx-template/internal/server/middleware"
+)
+
+// NewAdmin creates the router for the admin listener: health, readiness,
+// metrics, pprof and runtime log-level control. It only recovers from panics;
+// the public rate limiting and CSRF middleware do not apply, so it must never
+// be exposed publicly.
+func NewAdmin(
+	logger *slog.Logger,
+	level *slog.LevelVar,
+	metrics *middleware.Metrics,
+	readiness *health.Readiness,
+) http.Handler {
+	h := handler.New(logger, nil)
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc(newPath(http.MethodGet, "/health"), h.Health)
+	mux.HandleFunc(newPath(http.MethodGet, "/readyz"), h.ReadyDetail(readiness))
+	mux.Handle(newPath(http.MethodGet, "/metrics"), metrics)
+	mux.HandleFunc(newPath(http.MethodGet, "/log/level"), h.LogLevel(level))
+	mux.HandleFunc(newPath(http.MethodPut, "/log/level"), h.LogLevel(level))
+
+	mux.HandleFunc(newPath(http.MethodGet, "/debug/pprof/"), pprof.Index)
+	mux.HandleFunc(newPath(http.MethodGet, "/debug/pprof/cmdline"), pprof.Cmdline)
+	mux.HandleFunc(newPath(http.MethodGet, "/debug/pprof/profile"), pprof.Profile)
+	mux.HandleFunc(newPath(http.MethodGet, "/debug/pprof/symbol"), pprof.Symbol)
+	mux.HandleFunc(newPath(http.MethodPost, "/debug/pprof/symbol"), pprof.Symbol)
+	mux.HandleFunc(newPath(http.MethodGet, "/debug/pprof/trace"), pprof.Trace)
+
+	return middleware.Recovery(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+	}))(mux)
+}
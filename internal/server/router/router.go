@@ -1,43 +1,63 @@
 package router
 
 import (
-	"context"
 	"log/slog"
 	"net/http"
 
 	"go-htmx-template/internal/db"
 	"go-htmx-template/internal/dist"
+	"go-htmx-template/internal/health"
 	"go-htmx-template/internal/server/handler"
 	"go-htmx-template/internal/server/middleware"
-	"go-htmx-template/internal/version"
 )
 
-// New creates a new router with the given context, logger, database, and rate limit.
-func New(ctx context.Context, logger *slog.Logger, database db.Database, rateLimit int) http.Handler {
+// New creates a new router with the given logger, database, rate limiter,
+// client IP settings, metrics, readiness checks, and the routes allowed
+// longer timeouts.
+func New(
+	logger *slog.Logger,
+	database db.Database,
+	limiter *middleware.RateLimiter,
+	ipCfg middleware.IPConfig,
+	metrics *middleware.Metrics,
+	readiness *health.Readiness,
+	long middleware.LongRequests,
+) http.Handler {
 	h := handler.New(logger, database)
 
-	ipCfg := middleware.IPConfig{
-		TrustProxyHeaders: version.Value != "dev",
-	}
-
 	mux := http.NewServeMux()
 
 	// Routes
 	mux.HandleFunc(newPath(http.MethodGet, "/health"), h.Health)
+	mux.HandleFunc(newPath(http.MethodGet, "/readyz"), h.Ready(readiness))
 	mux.Handle(newPath(http.MethodGet, "/assets/"), middleware.CacheMiddleware(http.FileServer(http.FS(dist.AssetsDir))))
 	mux.HandleFunc(newPath(http.MethodGet, "/{$}"), h.Home)
 	mux.HandleFunc(newPath(http.MethodPost, "/count"), h.Count)
+	mux.HandleFunc(newPath(http.MethodPost, "/count/{name}"), h.Count)
+	mux.HandleFunc(newPath(http.MethodGet, "/authors"), h.Authors)
+	mux.HandleFunc(newPath(http.MethodPost, "/authors"), h.CreateAuthor)
+	mux.HandleFunc(newPath(http.MethodGet, "/authors/search"), h.SearchAuthors)
+	mux.HandleFunc(newPath(http.MethodGet, "/authors/{id}"), h.AuthorRow)
+	mux.HandleFunc(newPath(http.MethodGet, "/authors/{id}/edit"), h.EditAuthor)
+	mux.HandleFunc(newPath(http.MethodPut, "/authors/{id}"), h.UpdateAuthor)
+	mux.HandleFunc(newPath(http.MethodDelete, "/authors/{id}"), h.DeleteAuthor)
+	mux.HandleFunc(newPath(http.MethodPost, "/authors/{id}/restore"), h.RestoreAuthor)
+	mux.HandleFunc(newPath(http.MethodGet, "/authors/trash"), h.Trash)
+	mux.HandleFunc(newPath(http.MethodDelete, "/authors/trash/{id}"), h.PurgeAuthor)
 
 	// Middleware chain
 	hdlr := http.Handler(mux)
 	hdlr = middleware.Chain(
+		middleware.RequestID(ipCfg),
 		middleware.Recovery(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
 		})),
 		middleware.Logging(logger, ipCfg),
+		metrics.Middleware(),
 		middleware.Security(logger, ipCfg),
-		middleware.RateLimit(ctx, logger, rateLimit, middleware.DefaultMaxEntries, ipCfg),
+		limiter.Middleware(),
 		middleware.CSRF(logger, ipCfg),
+		long.Middleware(logger),
 	)(hdlr)
 
 	return hdlr
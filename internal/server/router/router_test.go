@@ -0,0 +1,50 @@
+package router_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go-htmx-template/internal/health"
+	"go-htmx-template/internal/server/middleware"
+	"go-htmx-template/internal/server/router"
+)
+
+func TestNew_LongRequests(t *testing.T) {
+	t.Parallel()
+
+	logger := slog.New(slog.DiscardHandler)
+	ipCfg := middleware.IPConfig{}
+	limiter := middleware.NewRateLimiter(t.Context(), logger, 100, middleware.DefaultMaxEntries, ipCfg)
+	hdlr := router.New(logger, nil, limiter, ipCfg, middleware.NewMetrics(), health.NewReadiness(),
+		middleware.LongRequests{Paths: []string{"/health"}, Timeout: time.Second})
+
+	// A write timeout this short fails any response not given a longer one.
+	srv := httptest.NewUnstartedServer(hdlr)
+	srv.Config.WriteTimeout = time.Nanosecond
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	get := func(path string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, srv.URL+path, nil)
+		require.NoError(t, err)
+		return srv.Client().Do(req)
+	}
+
+	resp, err := get("/health")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "version")
+
+	_, err = get("/readyz")
+	require.Error(t, err, "routes outside LONG_REQUEST_PATHS keep the server's write timeout")
+}
@@ -2,23 +2,30 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
+
+	"go-htmx-template/internal/config"
 )
 
-const (
-	writeTimeout      = 15 * time.Second
-	readTimeout       = 15 * time.Second
-	idleTimeout       = 60 * time.Second
-	readHeaderTimeout = 5 * time.Second
-	maxHeaderBytes    = 1 << 20
-	shutdownTimeout   = 10 * time.Second
+const adminWriteTimeout = 2 * time.Minute
+
+var (
+	errNegativeTimeout        = errors.New("timeout must not be negative")
+	errReadHeaderTimeout      = errors.New("read header timeout must not exceed read timeout")
+	errInvalidShutdownTimeout = errors.New("shutdown timeout must be positive")
+	errInvalidMaxHeaderBytes  = errors.New("max header bytes must be positive")
 )
 
 // Server represents an HTTP server.
@@ -26,20 +33,41 @@ type Server struct {
 	srv    *http.Server
 	logger *slog.Logger
 	errCh  chan error
+
+	shutdownTimeout time.Duration
+
+	extraAddrs   []string
+	socketMode   os.FileMode
+	certFile     string
+	keyFile      string
+	redirectAddr string
+	adminSrv     *http.Server
+	onShutdown   []func()
+	hooks        []namedHook
+
+	mu          sync.Mutex
+	lns         []net.Listener
+	redirectLn  net.Listener
+	redirectSrv *http.Server
+	adminLn     net.Listener
+	stopWatch   context.CancelFunc
+
+	// handoffFn hands the listeners to a new process; tests replace it.
+	handoffFn func() error
 }
 
-// New creates a new server with the given logger, address and options.
+// New creates a new server with the given logger, address and options. addr is
+// either a TCP address such as ":8080" or a unix socket such as
+// "unix:///run/app.sock".
 func New(logger *slog.Logger, addr string, opts ...Option) *Server {
-	srv := &http.Server{
-		Addr:              addr,
-		WriteTimeout:      writeTimeout,
-		ReadTimeout:       readTimeout,
-		IdleTimeout:       idleTimeout,
-		ReadHeaderTimeout: readHeaderTimeout,
-		MaxHeaderBytes:    maxHeaderBytes,
+	server := &Server{
+		srv:             newHTTPServer(addr),
+		logger:          logger,
+		errCh:           make(chan error, 1),
+		shutdownTimeout: config.DefaultShutdownTimeout,
+		socketMode:      defaultSocketMode,
 	}
-
-	server := &Server{srv: srv, logger: logger, errCh: make(chan error, 1)}
+	server.handoffFn = server.handoff
 	for _, opt := range opts {
 		opt(server)
 	}
@@ -47,6 +75,17 @@ func New(logger *slog.Logger, addr string, opts ...Option) *Server {
 	return server
 }
 
+func newHTTPServer(addr string) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		WriteTimeout:      config.DefaultWriteTimeout,
+		ReadTimeout:       config.DefaultReadTimeout,
+		IdleTimeout:       config.DefaultIdleTimeout,
+		ReadHeaderTimeout: config.DefaultReadHeaderTimeout,
+		MaxHeaderBytes:    config.DefaultMaxHeaderBytes,
+	}
+}
+
 // Option represents a server option.
 type Option func(*Server)
 
@@ -57,48 +96,506 @@ func WithRouter(handler http.Handler) Option {
 	}
 }
 
-// StartAndWait starts the server and waits for a signal to shut down.
+// WithWriteTimeout sets the maximum time to write a response, measured from
+// the end of the request headers. Zero means no timeout. Individual routes
+// can extend it with middleware.Deadlines.
+func WithWriteTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.srv.WriteTimeout = d
+	}
+}
+
+// WithReadTimeout sets the maximum time to read an entire request, including
+// the body. Zero means no timeout.
+func WithReadTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.srv.ReadTimeout = d
+	}
+}
+
+// WithIdleTimeout sets how long a keep-alive connection may wait for the next
+// request. Zero falls back to the read timeout.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.srv.IdleTimeout = d
+	}
+}
+
+// WithReadHeaderTimeout sets the maximum time to read request headers. Zero
+// falls back to the read timeout.
+func WithReadHeaderTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.srv.ReadHeaderTimeout = d
+	}
+}
+
+// WithMaxHeaderBytes sets the maximum size of request headers.
+func WithMaxHeaderBytes(n int) Option {
+	return func(s *Server) {
+		s.srv.MaxHeaderBytes = n
+	}
+}
+
+// WithShutdownTimeout sets how long StartAndWait waits for requests to drain
+// and shutdown hooks to finish before giving up. Hooks that start after it
+// passes each get a short grace period; see WithShutdownHook.
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.shutdownTimeout = d
+	}
+}
+
+// WithAddrs serves the same handler on addrs in addition to the address passed
+// to New. Each may be a TCP address or a unix socket.
+func WithAddrs(addrs ...string) Option {
+	return func(s *Server) {
+		s.extraAddrs = append(s.extraAddrs, addrs...)
+	}
+}
+
+// WithUnixSocketMode sets the file mode of unix sockets created by the server.
+func WithUnixSocketMode(mode os.FileMode) Option {
+	return func(s *Server) {
+		s.socketMode = mode
+	}
+}
+
+// WithTLS serves HTTPS using the given certificate and key files. The pair is
+// reloaded on SIGHUP or when either file changes on disk.
+func WithTLS(certFile, keyFile string) Option {
+	return func(s *Server) {
+		s.certFile = certFile
+		s.keyFile = keyFile
+	}
+}
+
+// WithH2C additionally accepts unencrypted HTTP/2 (h2c) on the plain HTTP
+// listeners, for proxies that speak HTTP/2 to their upstreams. Clients must
+// use prior knowledge; the HTTP/1.1 Upgrade mechanism is not supported.
+// HTTP/1.1 keeps working, as does HTTP/2 over TLS.
+func WithH2C() Option {
+	return func(s *Server) {
+		var protocols http.Protocols
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+		s.srv.Protocols = &protocols
+	}
+}
+
+// WithHTTPRedirect runs a plain HTTP listener on addr that redirects every
+// request to HTTPS. It only takes effect together with WithTLS.
+func WithHTTPRedirect(addr string) Option {
+	return func(s *Server) {
+		s.redirectAddr = addr
+	}
+}
+
+// WithAdmin runs handler on a separate plain HTTP listener at addr, intended
+// for operational endpoints that must not be exposed publicly. It is shut down
+// after the main listener has drained.
+func WithAdmin(addr string, handler http.Handler) Option {
+	return func(s *Server) {
+		s.adminSrv = newHTTPServer(addr)
+		s.adminSrv.Handler = handler
+		// Profiling endpoints stream for longer than the public write timeout.
+		s.adminSrv.WriteTimeout = adminWriteTimeout
+	}
+}
+
+// WithOnShutdown registers fn to run as soon as Shutdown begins, before any
+// listener stops accepting connections. Use it to fail readiness checks so
+// load balancers stop routing new traffic while requests drain.
+func WithOnShutdown(fn func()) Option {
+	return func(s *Server) {
+		s.onShutdown = append(s.onShutdown, fn)
+	}
+}
+
+// AdminAddr returns the address the admin listener is bound to, or nil if
+// there is none.
+func (s *Server) AdminAddr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.adminLn == nil {
+		return nil
+	}
+	return s.adminLn.Addr()
+}
+
+// StartAndWait starts the server and waits for a signal to shut down. On unix,
+// SIGUSR2 restarts the binary without dropping connections: the listeners are
+// passed to a new process and this one drains once the new one is serving.
 func (s *Server) StartAndWait() error {
-	s.start()
+	if err := s.Start(context.Background()); err != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+		defer cancel()
+		return errors.Join(err, s.runShutdownHooks(ctx))
+	}
+	if err := notifyHandoffReady(); err != nil {
+		s.logger.Error("failed to notify parent process", "error", err)
+	}
 	return s.gracefulShutdown()
 }
 
-func (s *Server) start() {
+// Start binds the server's listeners and serves them in the background. It
+// returns once the server is accepting connections on every address, so Addr
+// is valid as soon as Start returns. ctx only bounds binding; use Shutdown to
+// stop the server.
+//
+// Listeners passed in by systemd socket activation or by a restarting parent
+// (see StartAndWait) are used instead of binding new ones. They are matched by
+// position: one per address, then the HTTPS redirect listener.
+func (s *Server) Start(ctx context.Context) error {
+	if err := s.validate(); err != nil {
+		return err
+	}
+
+	inherited, err := inheritedListeners()
+	if err != nil {
+		return fmt.Errorf("inheriting listeners: %w", err)
+	}
+	if len(inherited) > 0 {
+		s.logger.Info("using inherited listeners", "count", len(inherited))
+	}
+
+	// Bind everything up front, in inheritance order: one listener per
+	// address, then the redirect listener, then the admin listener.
+	addrs := append([]string{s.srv.Addr}, s.extraAddrs...)
+	specs := slices.Clone(addrs)
+	withRedirect := s.tlsEnabled() && s.redirectAddr != ""
+	if withRedirect {
+		specs = append(specs, s.redirectAddr)
+	}
+	if s.adminSrv != nil {
+		specs = append(specs, s.adminSrv.Addr)
+	}
+
+	bound := make([]net.Listener, 0, len(specs))
+	closeAll := func() {
+		for _, l := range bound {
+			_ = l.Close()
+		}
+		for _, l := range inherited[min(len(bound), len(inherited)):] {
+			_ = l.Close()
+		}
+	}
+	for i, addr := range specs {
+		ln, lerr := s.listenOrInherit(ctx, inherited, i, addr)
+		if lerr != nil {
+			closeAll()
+			return lerr
+		}
+		bound = append(bound, ln)
+	}
+	for _, l := range inherited[min(len(bound), len(inherited)):] {
+		_ = l.Close()
+	}
+
+	lns := bound[:len(addrs)]
+	extra := bound[len(addrs):]
+	if err = s.prepare(lns...); err != nil {
+		closeAll()
+		return err
+	}
+
+	if withRedirect {
+		redirectLn := extra[0]
+		extra = extra[1:]
+		redirectSrv := newHTTPServer(s.redirectAddr)
+		s.applyLimits(redirectSrv)
+		redirectSrv.Handler = redirectHandler(firstTCPAddr(lns))
+		s.mu.Lock()
+		s.redirectLn = redirectLn
+		s.redirectSrv = redirectSrv
+		s.mu.Unlock()
+		s.serveAux("HTTPS redirect server", redirectSrv, redirectLn)
+	}
+
+	if s.adminSrv != nil {
+		s.applyLimits(s.adminSrv)
+		s.mu.Lock()
+		s.adminLn = extra[0]
+		s.mu.Unlock()
+		s.serveAux("admin server", s.adminSrv, extra[0])
+	}
+
+	for _, ln := range lns {
+		go func() {
+			if serr := s.serve(ln); serr != nil {
+				s.reportErr(serr)
+			}
+		}()
+	}
+
+	return nil
+}
+
+// serveAux serves one of the plain HTTP side listeners in the background.
+func (s *Server) serveAux(name string, srv *http.Server, ln net.Listener) {
 	go func() {
-		s.logger.Info("starting server", "port", s.srv.Addr)
-		if err := s.srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			s.errCh <- err
+		s.logger.Info("starting "+name, "addr", ln.Addr().String())
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.reportErr(fmt.Errorf("%s: %w", name, err))
 		}
 	}()
 }
 
-func (s *Server) gracefulShutdown() error {
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+// listenOrInherit returns the i-th inherited listener if there is one, and
+// otherwise binds addr.
+func (s *Server) listenOrInherit(ctx context.Context, inherited []net.Listener, i int, addr string) (net.Listener, error) {
+	if i < len(inherited) {
+		return inherited[i], nil
+	}
+	return s.listen(ctx, addr)
+}
 
-	// Block until we receive a shutdown signal or a fatal server error.
+// reportErr records a fatal serve error without blocking when one is already
+// pending; the first error is enough to trigger shutdown.
+func (s *Server) reportErr(err error) {
 	select {
-	case <-sig:
-		signal.Stop(sig)
-		// Drain errCh: if the server failed to start concurrently, return that error.
-		select {
-		case err := <-s.errCh:
-			return fmt.Errorf("server failed to start: %w", err)
-		default:
-		}
-	case err := <-s.errCh:
-		return fmt.Errorf("server failed to start: %w", err)
+	case s.errCh <- err:
+	default:
+	}
+}
+
+// Serve accepts connections on ln and blocks until the server is shut down.
+// It returns nil after a graceful Shutdown. Serve may be called for several
+// listeners concurrently. The HTTPS redirect listener is only run by Start.
+func (s *Server) Serve(ln net.Listener) error {
+	if err := s.validate(); err != nil {
+		return err
+	}
+	if err := s.prepare(ln); err != nil {
+		return err
+	}
+	return s.serve(ln)
+}
+
+// Addr returns the first address the server is bound to, or nil if it has not
+// been started.
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.lns) == 0 {
+		return nil
+	}
+	return s.lns[0].Addr()
+}
+
+// Addrs returns every address the server is bound to, in the order they were
+// configured.
+func (s *Server) Addrs() []net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	addrs := make([]net.Addr, 0, len(s.lns))
+	for _, ln := range s.lns {
+		addrs = append(addrs, ln.Addr())
 	}
+	return addrs
+}
 
+// Shutdown gracefully stops the server, waiting for in-flight requests to
+// finish or ctx to expire, then runs the registered shutdown hooks within the
+// same deadline.
+func (s *Server) Shutdown(ctx context.Context) error {
 	s.logger.Info("shutting down server")
 
-	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
-	defer cancel()
+	for _, fn := range s.onShutdown {
+		fn()
+	}
+
+	s.stopBackground()
+
+	s.mu.Lock()
+	redirectSrv := s.redirectSrv
+	s.mu.Unlock()
+
+	var errs []error
+	if redirectSrv != nil {
+		if err := redirectSrv.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("redirect server shutdown: %w", err))
+		}
+	}
 
 	if err := s.srv.Shutdown(ctx); err != nil {
-		return fmt.Errorf("server shutdown: %w", err)
+		errs = append(errs, fmt.Errorf("server shutdown: %w", err))
+	}
+
+	// The admin listener stays up while requests drain so operators can watch.
+	if s.adminSrv != nil {
+		if err := s.adminSrv.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("admin server shutdown: %w", err))
+		}
+	}
+
+	// Hooks run even if draining timed out so resources are still released.
+	if err := s.runShutdownHooks(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return err
 	}
 
 	s.logger.Info("server stopped")
 	return nil
 }
+
+// validate reports every invalid limit set through options.
+func (s *Server) validate() error {
+	timeouts := []struct {
+		name string
+		d    time.Duration
+	}{
+		{"write timeout", s.srv.WriteTimeout},
+		{"read timeout", s.srv.ReadTimeout},
+		{"idle timeout", s.srv.IdleTimeout},
+		{"read header timeout", s.srv.ReadHeaderTimeout},
+	}
+
+	var errs []error
+	for _, t := range timeouts {
+		if t.d < 0 {
+			errs = append(errs, fmt.Errorf("%w: %s is %s", errNegativeTimeout, t.name, t.d))
+		}
+	}
+	if s.srv.ReadTimeout > 0 && s.srv.ReadHeaderTimeout > s.srv.ReadTimeout {
+		errs = append(errs, fmt.Errorf("%w: %s > %s", errReadHeaderTimeout, s.srv.ReadHeaderTimeout, s.srv.ReadTimeout))
+	}
+	if s.shutdownTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("%w: %s", errInvalidShutdownTimeout, s.shutdownTimeout))
+	}
+	if s.srv.MaxHeaderBytes <= 0 {
+		errs = append(errs, fmt.Errorf("%w: %d", errInvalidMaxHeaderBytes, s.srv.MaxHeaderBytes))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("invalid server options: %w", err)
+	}
+	return nil
+}
+
+// applyLimits copies the configured read limits to a side listener's server.
+// Its write timeout is left alone: the admin listener needs a longer one.
+func (s *Server) applyLimits(srv *http.Server) {
+	srv.ReadTimeout = s.srv.ReadTimeout
+	srv.ReadHeaderTimeout = s.srv.ReadHeaderTimeout
+	srv.IdleTimeout = s.srv.IdleTimeout
+	srv.MaxHeaderBytes = s.srv.MaxHeaderBytes
+}
+
+func (s *Server) tlsEnabled() bool {
+	return s.certFile != "" || s.keyFile != ""
+}
+
+// prepare records lns as bound listeners and, when TLS is enabled, loads the
+// certificate pair and starts watching it for changes.
+func (s *Server) prepare(lns ...net.Listener) error {
+	s.mu.Lock()
+	s.lns = append(s.lns, lns...)
+	watching := s.stopWatch != nil
+	s.mu.Unlock()
+
+	if !s.tlsEnabled() || watching {
+		return nil
+	}
+
+	certs, err := newCertReloader(s.logger, s.certFile, s.keyFile)
+	if err != nil {
+		return fmt.Errorf("server failed to start: %w", err)
+	}
+	s.srv.TLSConfig = &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certs.GetCertificate,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.mu.Lock()
+	s.stopWatch = cancel
+	s.mu.Unlock()
+	go certs.watch(ctx)
+
+	return nil
+}
+
+func (s *Server) serve(ln net.Listener) error {
+	var err error
+	if s.tlsEnabled() {
+		s.logger.Info("starting server", "addr", ln.Addr().String(), "tls", true)
+		err = s.srv.ServeTLS(ln, "", "")
+	} else {
+		s.logger.Info("starting server", "addr", ln.Addr().String())
+		err = s.srv.Serve(ln)
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("serving: %w", err)
+	}
+	return nil
+}
+
+func (s *Server) stopBackground() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopWatch != nil {
+		s.stopWatch()
+		s.stopWatch = nil
+	}
+}
+
+// gracefulShutdown blocks until a shutdown signal or a fatal server error,
+// then shuts the server down. On SIGUSR2 the listeners are handed to a new
+// copy of the binary and this process drains once the copy is serving.
+func (s *Server) gracefulShutdown() error {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+
+	restart, stopRestart := notifyRestart()
+	defer stopRestart()
+
+	return s.awaitShutdown(sig, restart)
+}
+
+// awaitShutdown is the loop of gracefulShutdown. Only one handoff runs at a
+// time, and after one succeeds no other starts: the child owns the listeners.
+func (s *Server) awaitShutdown(sig, restart <-chan os.Signal) error {
+	handedOff := make(chan struct{})
+	var closeHandedOff sync.Once
+	var restarting atomic.Bool
+
+	for {
+		select {
+		case <-sig:
+			return s.shutdownWithTimeout()
+		case err := <-s.errCh:
+			return errors.Join(fmt.Errorf("server error: %w", err), s.shutdownWithTimeout())
+		case <-restart:
+			if !restarting.CompareAndSwap(false, true) {
+				s.logger.Warn("restart already in progress")
+				continue
+			}
+			go func() {
+				if err := s.handoffFn(); err != nil {
+					s.logger.Error("listener handoff failed; continuing to serve", "error", err)
+					restarting.Store(false)
+					return
+				}
+				closeHandedOff.Do(func() { close(handedOff) })
+			}()
+		case <-handedOff:
+			s.logger.Info("child process is serving; draining")
+			return s.shutdownWithTimeout()
+		}
+	}
+}
+
+func (s *Server) shutdownWithTimeout() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	return s.Shutdown(ctx)
+}
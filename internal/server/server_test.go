@@ -0,0 +1,563 @@
+package server_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go-htmx-template/internal/server"
+)
+
+func newTestServer(handler http.Handler, opts ...server.Option) *server.Server {
+	opts = append([]server.Option{server.WithRouter(handler)}, opts...)
+	return server.New(slog.New(slog.DiscardHandler), "127.0.0.1:0", opts...)
+}
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	})
+}
+
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, url, nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(body)
+}
+
+func TestServer_AddrBeforeStart(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(okHandler())
+	assert.Nil(t, srv.Addr())
+}
+
+func TestServer_StartBindsEphemeralPort(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(okHandler())
+	require.NoError(t, srv.Start(t.Context()))
+	t.Cleanup(func() { _ = srv.Shutdown(context.Background()) })
+
+	addr, ok := srv.Addr().(*net.TCPAddr)
+	require.True(t, ok)
+	assert.NotZero(t, addr.Port)
+
+	status, body := get(t, "http://"+addr.String()+"/")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "ok", body)
+}
+
+func TestServer_StartReturnsBindError(t *testing.T) {
+	t.Parallel()
+
+	var lc net.ListenConfig
+	ln, err := lc.Listen(t.Context(), "tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	srv := server.New(slog.New(slog.DiscardHandler), ln.Addr().String(), server.WithRouter(okHandler()))
+	require.Error(t, srv.Start(t.Context()))
+	assert.Nil(t, srv.Addr())
+}
+
+func TestServer_ServeReturnsNilAfterShutdown(t *testing.T) {
+	t.Parallel()
+
+	var lc net.ListenConfig
+	ln, err := lc.Listen(t.Context(), "tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := newTestServer(okHandler())
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Serve(ln) }()
+
+	require.Eventually(t, func() bool { return srv.Addr() != nil }, time.Second, 10*time.Millisecond)
+	status, _ := get(t, "http://"+ln.Addr().String()+"/")
+	assert.Equal(t, http.StatusOK, status)
+
+	require.NoError(t, srv.Shutdown(t.Context()))
+	require.NoError(t, <-errCh)
+}
+
+func TestServer_ShutdownDrainsInFlightRequests(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	srv := newTestServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		_, _ = io.WriteString(w, "done")
+	}))
+	require.NoError(t, srv.Start(t.Context()))
+	url := "http://" + srv.Addr().String() + "/"
+
+	respCh := make(chan *http.Response, 1)
+	go func() {
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			respCh <- nil
+			return
+		}
+		respCh <- resp
+	}()
+	<-started
+
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- srv.Shutdown(context.Background()) }()
+
+	select {
+	case <-shutdownErr:
+		t.Fatal("Shutdown returned before the in-flight request finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	resp := <-respCh
+	require.NotNil(t, resp)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "done", string(body))
+	require.NoError(t, <-shutdownErr)
+}
+
+func TestServer_ShutdownHooksRunInReverseOrder(t *testing.T) {
+	t.Parallel()
+
+	var order []string
+	hook := func(name string) server.Option {
+		return server.WithShutdownHook(name, func(context.Context) error {
+			order = append(order, name)
+			return nil
+		})
+	}
+
+	srv := newTestServer(okHandler(), hook("database"), hook("cache"), hook("workers"))
+	require.NoError(t, srv.Start(t.Context()))
+	require.NoError(t, srv.Shutdown(t.Context()))
+
+	assert.Equal(t, []string{"workers", "cache", "database"}, order)
+}
+
+func TestServer_ShutdownHooksRunAfterDrain(t *testing.T) {
+	t.Parallel()
+
+	var inFlight atomic.Bool
+	started := make(chan struct{})
+	srv := newTestServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			inFlight.Store(true)
+			close(started)
+			time.Sleep(50 * time.Millisecond)
+			inFlight.Store(false)
+		}),
+		server.WithShutdownHook("database", func(context.Context) error {
+			if inFlight.Load() {
+				return errors.New("closed while a request was in flight")
+			}
+			return nil
+		}),
+	)
+	require.NoError(t, srv.Start(t.Context()))
+
+	go func() {
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://"+srv.Addr().String()+"/", nil)
+		if resp, err := http.DefaultClient.Do(req); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+
+	require.NoError(t, srv.Shutdown(t.Context()))
+}
+
+func TestServer_ShutdownHookErrorsAreReturned(t *testing.T) {
+	t.Parallel()
+
+	var ran bool
+	errBoom := errors.New("boom")
+	srv := newTestServer(okHandler(),
+		server.WithShutdownHook("first", func(context.Context) error {
+			ran = true
+			return nil
+		}),
+		server.WithShutdownHook("second", func(context.Context) error { return errBoom }),
+	)
+	require.NoError(t, srv.Start(t.Context()))
+
+	err := srv.Shutdown(t.Context())
+	require.ErrorIs(t, err, errBoom)
+	assert.True(t, ran, "hooks after a failing hook should still run")
+}
+
+func TestServer_ShutdownHookRespectsDeadline(t *testing.T) {
+	t.Parallel()
+
+	var ran atomic.Bool
+	srv := newTestServer(okHandler(),
+		server.WithShutdownHook("database", func(context.Context) error {
+			ran.Store(true)
+			return nil
+		}),
+		server.WithShutdownHook("stuck", func(context.Context) error {
+			select {}
+		}),
+	)
+	require.NoError(t, srv.Start(t.Context()))
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+
+	err := srv.Shutdown(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.True(t, ran.Load(), "hooks after a stuck hook should still run")
+}
+
+func TestServer_LateShutdownHookGetsGrace(t *testing.T) {
+	t.Parallel()
+
+	var closed atomic.Bool
+	srv := newTestServer(okHandler(),
+		server.WithShutdownHook("database", func(ctx context.Context) error {
+			// Respects ctx, as a real close would.
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(10 * time.Millisecond):
+				closed.Store(true)
+				return nil
+			}
+		}),
+		server.WithShutdownHook("stuck", func(context.Context) error {
+			select {}
+		}),
+	)
+	require.NoError(t, srv.Start(t.Context()))
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+
+	err := srv.Shutdown(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotContains(t, err.Error(), "database")
+	assert.True(t, closed.Load(), "a hook started after the deadline should get time to run")
+}
+
+// socketPath returns a short unix socket path; t.TempDir can exceed the
+// platform limit on socket path length.
+func socketPath(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "srv")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	return filepath.Join(dir, "app.sock")
+}
+
+func unixClient(path string) *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}}
+}
+
+func TestServer_UnixSocket(t *testing.T) {
+	t.Parallel()
+
+	path := socketPath(t)
+	srv := server.New(slog.New(slog.DiscardHandler), "unix://"+path,
+		server.WithRouter(okHandler()),
+		server.WithUnixSocketMode(0o600),
+	)
+	require.NoError(t, srv.Start(t.Context()))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, "http://app/", nil)
+	require.NoError(t, err)
+	resp, err := unixClient(path).Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "ok", string(body))
+
+	require.NoError(t, srv.Shutdown(t.Context()))
+	_, err = os.Stat(path)
+	assert.ErrorIs(t, err, fs.ErrNotExist, "socket file should be removed on shutdown")
+}
+
+func TestServer_UnixSocketReplacesStaleSocket(t *testing.T) {
+	t.Parallel()
+
+	path := socketPath(t)
+	var lc net.ListenConfig
+	stale, err := lc.Listen(t.Context(), "unix", path)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	srv := server.New(slog.New(slog.DiscardHandler), "unix://"+path, server.WithRouter(okHandler()))
+	require.NoError(t, srv.Start(t.Context()))
+	require.NoError(t, srv.Shutdown(t.Context()))
+}
+
+func TestServer_UnixSocketInUse(t *testing.T) {
+	t.Parallel()
+
+	path := socketPath(t)
+	first := server.New(slog.New(slog.DiscardHandler), "unix://"+path, server.WithRouter(okHandler()))
+	require.NoError(t, first.Start(t.Context()))
+	t.Cleanup(func() { _ = first.Shutdown(context.Background()) })
+
+	second := server.New(slog.New(slog.DiscardHandler), "unix://"+path, server.WithRouter(okHandler()))
+	require.Error(t, second.Start(t.Context()))
+}
+
+func TestServer_UnixSocketRefusesRegularFile(t *testing.T) {
+	t.Parallel()
+
+	path := socketPath(t)
+	require.NoError(t, os.WriteFile(path, []byte("data"), 0o600))
+
+	srv := server.New(slog.New(slog.DiscardHandler), "unix://"+path, server.WithRouter(okHandler()))
+	require.Error(t, srv.Start(t.Context()))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(data), "regular files must not be removed")
+}
+
+func TestServer_MultipleAddrs(t *testing.T) {
+	t.Parallel()
+
+	path := socketPath(t)
+	srv := newTestServer(okHandler(), server.WithAddrs("127.0.0.1:0", "unix://"+path))
+	require.NoError(t, srv.Start(t.Context()))
+	t.Cleanup(func() { _ = srv.Shutdown(context.Background()) })
+
+	addrs := srv.Addrs()
+	require.Len(t, addrs, 3)
+	assert.Equal(t, srv.Addr(), addrs[0])
+
+	for _, addr := range addrs[:2] {
+		status, body := get(t, "http://"+addr.String()+"/")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "ok", body)
+	}
+	assert.Equal(t, path, addrs[2].String())
+}
+
+func TestServer_MultipleAddrsBindFailureReleasesListeners(t *testing.T) {
+	t.Parallel()
+
+	var lc net.ListenConfig
+	busy, err := lc.Listen(t.Context(), "tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = busy.Close() })
+
+	path := socketPath(t)
+	srv := server.New(slog.New(slog.DiscardHandler), "unix://"+path,
+		server.WithRouter(okHandler()),
+		server.WithAddrs(busy.Addr().String()),
+	)
+	require.Error(t, srv.Start(t.Context()))
+
+	_, err = os.Stat(path)
+	assert.ErrorIs(t, err, fs.ErrNotExist, "already-bound listeners should be closed")
+}
+
+func TestServer_AdminListener(t *testing.T) {
+	t.Parallel()
+
+	admin := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "admin")
+	})
+	srv := newTestServer(okHandler(), server.WithAdmin("127.0.0.1:0", admin))
+	assert.Nil(t, srv.AdminAddr())
+
+	require.NoError(t, srv.Start(t.Context()))
+	require.NotNil(t, srv.AdminAddr())
+	assert.NotEqual(t, srv.Addr().String(), srv.AdminAddr().String())
+
+	_, body := get(t, "http://"+srv.Addr().String()+"/")
+	assert.Equal(t, "ok", body)
+	_, body = get(t, "http://"+srv.AdminAddr().String()+"/")
+	assert.Equal(t, "admin", body)
+
+	adminAddr := srv.AdminAddr().String()
+	require.NoError(t, srv.Shutdown(t.Context()))
+
+	var d net.Dialer
+	_, err := d.DialContext(t.Context(), "tcp", adminAddr)
+	require.Error(t, err, "admin listener should be closed after shutdown")
+}
+
+func TestServer_OnShutdownRunsBeforeDrain(t *testing.T) {
+	t.Parallel()
+
+	var order []string
+	srv := newTestServer(okHandler(),
+		server.WithOnShutdown(func() { order = append(order, "on shutdown") }),
+		server.WithShutdownHook("hook", func(context.Context) error {
+			order = append(order, "hook")
+			return nil
+		}),
+	)
+	require.NoError(t, srv.Start(t.Context()))
+	require.NoError(t, srv.Shutdown(t.Context()))
+
+	assert.Equal(t, []string{"on shutdown", "hook"}, order)
+}
+
+func TestServer_InvalidLimits(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opt  server.Option
+	}{
+		{name: "negative write timeout", opt: server.WithWriteTimeout(-time.Second)},
+		{name: "negative idle timeout", opt: server.WithIdleTimeout(-time.Second)},
+		{name: "header timeout above read timeout", opt: server.WithReadHeaderTimeout(time.Minute)},
+		{name: "zero shutdown timeout", opt: server.WithShutdownTimeout(0)},
+		{name: "zero max header bytes", opt: server.WithMaxHeaderBytes(0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := newTestServer(okHandler(), tt.opt)
+			require.ErrorContains(t, srv.Start(t.Context()), "invalid server options")
+			assert.Nil(t, srv.Addr())
+		})
+	}
+}
+
+func TestServer_MaxHeaderBytes(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(okHandler(), server.WithMaxHeaderBytes(1024))
+	require.NoError(t, srv.Start(t.Context()))
+	t.Cleanup(func() { _ = srv.Shutdown(context.Background()) })
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, "http://"+srv.Addr().String()+"/", nil)
+	require.NoError(t, err)
+	req.Header.Set("X-Large", strings.Repeat("a", 8<<10))
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusRequestHeaderFieldsTooLarge, resp.StatusCode)
+}
+
+func h2cClient() *http.Client {
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	return &http.Client{Transport: &http.Transport{Protocols: &protocols}}
+}
+
+func TestServer_H2C(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(okHandler(), server.WithH2C())
+	require.NoError(t, srv.Start(t.Context()))
+	t.Cleanup(func() { _ = srv.Shutdown(context.Background()) })
+	url := "http://" + srv.Addr().String() + "/"
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, url, nil)
+	require.NoError(t, err)
+	resp, err := h2cClient().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, 2, resp.ProtoMajor)
+	assert.Equal(t, "ok", string(body))
+
+	status, body2 := get(t, url)
+	assert.Equal(t, http.StatusOK, status, "HTTP/1.1 should still be served")
+	assert.Equal(t, "ok", body2)
+}
+
+func TestServer_H2CDisabledByDefault(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(okHandler())
+	require.NoError(t, srv.Start(t.Context()))
+	t.Cleanup(func() { _ = srv.Shutdown(context.Background()) })
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, "http://"+srv.Addr().String()+"/", nil)
+	require.NoError(t, err)
+	resp, err := h2cClient().Do(req)
+	if err == nil {
+		resp.Body.Close()
+	}
+	require.Error(t, err)
+}
+
+func TestServer_H2CStreaming(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	sse := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		rc := http.NewResponseController(w)
+		for i := range 2 {
+			_, _ = fmt.Fprintf(w, "data: %d\n\n", i)
+			_ = rc.Flush()
+			if i == 0 {
+				<-release
+			}
+		}
+	})
+	srv := newTestServer(sse, server.WithH2C())
+	require.NoError(t, srv.Start(t.Context()))
+	t.Cleanup(func() { _ = srv.Shutdown(context.Background()) })
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, "http://"+srv.Addr().String()+"/", nil)
+	require.NoError(t, err)
+	resp, err := h2cClient().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, 2, resp.ProtoMajor)
+
+	// The first event arrives while the handler is still running.
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "data: 0\n", line)
+
+	close(release)
+	rest, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "\ndata: 1\n\n", string(rest))
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// ShutdownHook releases a resource once the server has stopped accepting
+// requests. ctx carries the remaining shutdown deadline.
+type ShutdownHook func(ctx context.Context) error
+
+type namedHook struct {
+	name string
+	fn   ShutdownHook
+}
+
+// WithShutdownHook registers fn to run after the HTTP server has drained.
+// Hooks run in reverse registration order, so resources should be registered
+// in the order they are created. A hook that starts after the shutdown
+// deadline has passed still gets 500ms to run, so shutdown can overrun its
+// timeout by that much for each such hook.
+func WithShutdownHook(name string, fn ShutdownHook) Option {
+	return func(s *Server) {
+		s.hooks = append(s.hooks, namedHook{name: name, fn: fn})
+	}
+}
+
+// lateHookGrace is how long a hook that starts after the shutdown deadline has
+// already passed may run before it is abandoned.
+const lateHookGrace = 500 * time.Millisecond
+
+// runShutdownHooks runs each registered hook once, last registered first. A
+// hook that outlives ctx is abandoned so the remaining hooks still get a chance
+// to run.
+func (s *Server) runShutdownHooks(ctx context.Context) error {
+	s.mu.Lock()
+	hooks := s.hooks
+	s.hooks = nil
+	s.mu.Unlock()
+
+	var errs []error
+	for _, hook := range slices.Backward(hooks) {
+		start := time.Now()
+		if ctx.Err() != nil {
+			s.logger.Warn("shutdown deadline passed; running hook with a grace period",
+				slog.String("hook", hook.name), slog.Duration("grace", lateHookGrace))
+		}
+		err := runHook(ctx, hook.fn)
+		attrs := []any{slog.String("hook", hook.name), slog.Duration("duration", time.Since(start))}
+		if err != nil {
+			s.logger.Error("shutdown hook failed", append(attrs, slog.Any("error", err))...)
+			errs = append(errs, fmt.Errorf("shutdown hook %s: %w", hook.name, err))
+			continue
+		}
+		s.logger.Debug("shutdown hook completed", attrs...)
+	}
+
+	return errors.Join(errs...)
+}
+
+// runHook runs fn and waits for it to finish or for ctx to expire. A hook
+// started after the deadline is given its own context lasting lateHookGrace,
+// so it can still release its resource instead of failing at once.
+func runHook(ctx context.Context, fn ShutdownHook) error {
+	if ctx.Err() != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(context.WithoutCancel(ctx), lateHookGrace, ctx.Err())
+		defer cancel()
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- fn(ctx) }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out: %w", context.Cause(ctx))
+	}
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const certPollInterval = 30 * time.Second
+
+// certReloader serves the current certificate pair to the TLS handshake and
+// swaps it when the files change. Existing connections keep the certificate they
+// negotiated with; only new handshakes see the reloaded pair.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	logger   *slog.Logger
+
+	cert atomic.Pointer[tls.Certificate]
+
+	mu      sync.Mutex
+	modTime time.Time
+}
+
+func newCertReloader(logger *slog.Logger, certFile, keyFile string) (*certReloader, error) {
+	c := &certReloader{certFile: certFile, keyFile: keyFile, logger: logger}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate.
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return c.cert.Load(), nil
+}
+
+func (c *certReloader) reload() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	modTime, err := c.latestModTime()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("loading certificate pair: %w", err)
+	}
+
+	c.cert.Store(&cert)
+	c.modTime = modTime
+	return nil
+}
+
+// changed reports whether either file has been modified since the last reload.
+func (c *certReloader) changed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	modTime, err := c.latestModTime()
+	if err != nil {
+		// A missing file is usually a rotation in progress; try again next tick.
+		return false
+	}
+	return modTime.After(c.modTime)
+}
+
+func (c *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, name := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("stat %s: %w", name, err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// watch reloads the certificate pair on SIGHUP or when the files change on disk
+// until ctx is cancelled. A failed reload keeps serving the previous pair.
+func (c *certReloader) watch(ctx context.Context) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	defer signal.Stop(sig)
+
+	ticker := time.NewTicker(certPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sig:
+			c.reloadAndLog("signal")
+		case <-ticker.C:
+			if c.changed() {
+				c.reloadAndLog("file change")
+			}
+		}
+	}
+}
+
+func (c *certReloader) reloadAndLog(trigger string) {
+	if err := c.reload(); err != nil {
+		c.logger.Error("failed to reload TLS certificate", "trigger", trigger, "error", err)
+		return
+	}
+	c.logger.Info("reloaded TLS certificate", "trigger", trigger, "cert_file", c.certFile)
+}
+
+// redirectHandler sends every request to the same host and path over HTTPS.
+// tlsAddr is the address the HTTPS listener is bound to; its port is kept in
+// the redirect unless it is the default 443.
+func redirectHandler(tlsAddr string) http.Handler {
+	_, tlsPort, _ := net.SplitHostPort(tlsAddr)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if tlsPort != "" && tlsPort != "443" {
+			host = net.JoinHostPort(host, tlsPort)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
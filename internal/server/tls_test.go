@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCertPair writes a self-signed certificate for commonName to dir and
+// returns the certificate and key file paths.
+func writeCertPair(t *testing.T, dir, commonName string) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func leafCommonName(t *testing.T, c *certReloader) string {
+	t.Helper()
+	cert, err := c.GetCertificate(nil)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	return leaf.Subject.CommonName
+}
+
+func TestCertReloader_LoadsPair(t *testing.T) {
+	t.Parallel()
+
+	certFile, keyFile := writeCertPair(t, t.TempDir(), "first.test")
+	c, err := newCertReloader(slog.New(slog.DiscardHandler), certFile, keyFile)
+	require.NoError(t, err)
+
+	assert.Equal(t, "first.test", leafCommonName(t, c))
+	assert.False(t, c.changed())
+}
+
+func TestCertReloader_MissingFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	_, err := newCertReloader(slog.New(slog.DiscardHandler), filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"))
+	require.Error(t, err)
+}
+
+func TestCertReloader_ReloadsOnChange(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	certFile, keyFile := writeCertPair(t, dir, "first.test")
+	c, err := newCertReloader(slog.New(slog.DiscardHandler), certFile, keyFile)
+	require.NoError(t, err)
+
+	writeCertPair(t, dir, "second.test")
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, future, future))
+
+	require.True(t, c.changed())
+	c.reloadAndLog("test")
+
+	assert.Equal(t, "second.test", leafCommonName(t, c))
+	assert.False(t, c.changed())
+}
+
+func TestCertReloader_KeepsPairOnFailedReload(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	certFile, keyFile := writeCertPair(t, dir, "first.test")
+	c, err := newCertReloader(slog.New(slog.DiscardHandler), certFile, keyFile)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, []byte("not a certificate"), 0o600))
+	c.reloadAndLog("test")
+
+	assert.Equal(t, "first.test", leafCommonName(t, c))
+}
+
+func TestRedirectHandler(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		tlsAddr  string
+		host     string
+		target   string
+		expected string
+	}{
+		{
+			name:     "default port is omitted",
+			tlsAddr:  ":443",
+			host:     "example.com",
+			target:   "/path?q=1",
+			expected: "https://example.com/path?q=1",
+		},
+		{
+			name:     "incoming port is replaced",
+			tlsAddr:  ":443",
+			host:     "example.com:80",
+			target:   "/",
+			expected: "https://example.com/",
+		},
+		{
+			name:     "non-default port is kept",
+			tlsAddr:  ":8443",
+			host:     "example.com:8080",
+			target:   "/count",
+			expected: "https://example.com:8443/count",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, tt.target, nil)
+			req.Host = tt.host
+			rec := httptest.NewRecorder()
+
+			redirectHandler(tt.tlsAddr).ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusPermanentRedirect, rec.Code)
+			assert.Equal(t, tt.expected, rec.Header().Get("Location"))
+		})
+	}
+}